@@ -0,0 +1,112 @@
+package clients
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// Backoff decides how long to wait before a connection retry, as the Backoff
+// setting, or a query retry, as QueryBackoff. attempt is 1 for the first retry
+// and grows by one for each retry after it. Implementations must be safe for
+// concurrent use. BackoffJitter or QueryBackoffJitter, when enabled, is applied
+// on top of the returned duration.
+type Backoff interface {
+	NextBackoff(attempt int) time.Duration
+}
+
+// ExponentialBackoff waits Initial before the first retry and doubles the wait for
+// each retry after it, never exceeding Max. It is the default strategy.
+type ExponentialBackoff struct {
+	Initial time.Duration
+	Max     time.Duration
+}
+
+func (b ExponentialBackoff) NextBackoff(attempt int) time.Duration {
+	backoff := b.Initial
+	for i := 1; i < attempt && backoff < b.Max; i++ {
+		backoff *= 2
+	}
+	if backoff > b.Max {
+		backoff = b.Max
+	}
+	return backoff
+}
+
+// ConstantBackoff waits the same Interval before every retry
+type ConstantBackoff struct {
+	Interval time.Duration
+}
+
+func (b ConstantBackoff) NextBackoff(int) time.Duration {
+	return b.Interval
+}
+
+// LinearBackoff waits Initial before the first retry and Step longer for each retry
+// after it, never exceeding Max
+type LinearBackoff struct {
+	Initial time.Duration
+	Step    time.Duration
+	Max     time.Duration
+}
+
+func (b LinearBackoff) NextBackoff(attempt int) time.Duration {
+	if attempt < 1 {
+		attempt = 1
+	}
+	backoff := b.Initial + time.Duration(attempt-1)*b.Step
+	if backoff > b.Max || backoff < 0 {
+		backoff = b.Max
+	}
+	return backoff
+}
+
+// DecorrelatedJitterBackoff picks each wait at random between Base and three times
+// the previous wait, never exceeding Max, as described in the AWS architecture blog.
+// It is randomized already, so BackoffJitter is best left off when using it. The
+// previous wait restarts from Base whenever attempt is 1.
+type DecorrelatedJitterBackoff struct {
+	Base time.Duration
+	Max  time.Duration
+
+	mu   sync.Mutex
+	prev time.Duration
+}
+
+func (b *DecorrelatedJitterBackoff) NextBackoff(attempt int) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if attempt <= 1 || b.prev < b.Base {
+		b.prev = b.Base
+	}
+	upper := b.prev * 3
+	if upper > b.Max || upper < 0 {
+		upper = b.Max
+	}
+
+	backoff := b.Base
+	if upper > b.Base {
+		backoff += time.Duration(rand.Int63n(int64(upper - b.Base)))
+	}
+	b.prev = backoff
+	return backoff
+}
+
+// backoff returns the configured strategy, defaulting to ExponentialBackoff over
+// InitialBackoff and MaxBackoff
+func (cqc *CosmosQueryClient) backoff() Backoff {
+	if cqc.config.Backoff != nil {
+		return cqc.config.Backoff
+	}
+	return ExponentialBackoff{Initial: cqc.config.InitialBackoff, Max: cqc.config.MaxBackoff}
+}
+
+// queryBackoffStrategy returns the configured query retry strategy, defaulting to
+// ExponentialBackoff over QueryInitialBackoff and QueryMaxBackoff
+func (cqc *CosmosQueryClient) queryBackoffStrategy() Backoff {
+	if cqc.config.QueryBackoff != nil {
+		return cqc.config.QueryBackoff
+	}
+	return ExponentialBackoff{Initial: cqc.config.QueryInitialBackoff, Max: cqc.config.QueryMaxBackoff}
+}
@@ -0,0 +1,102 @@
+package clients_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Layer-Edge/light-node/clients"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestExponentialBackoff(t *testing.T) {
+	b := clients.ExponentialBackoff{Initial: 30 * time.Second, Max: 5 * time.Minute}
+
+	want := []time.Duration{
+		30 * time.Second,  // The first retry waits Initial, not twice it
+		60 * time.Second,  // 2x
+		120 * time.Second, // 4x
+		240 * time.Second, // 8x
+		5 * time.Minute,   // 16x is capped at Max
+		5 * time.Minute,
+	}
+	for i, w := range want {
+		if got := b.NextBackoff(i + 1); got != w {
+			t.Errorf("NextBackoff(%d) = %v, want %v", i+1, got, w)
+		}
+	}
+
+	// Far past the cap the doubling must not overflow
+	if got := b.NextBackoff(1000); got != b.Max {
+		t.Errorf("NextBackoff(1000) = %v, want %v", got, b.Max)
+	}
+}
+
+// recordingBackoff records the attempts it is asked about
+type recordingBackoff struct {
+	mu       sync.Mutex
+	attempts []int
+}
+
+func (b *recordingBackoff) NextBackoff(attempt int) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.attempts = append(b.attempts, attempt)
+	return time.Millisecond
+}
+
+// checkAttempts fails unless backoff was asked about exactly want, in order
+func checkAttempts(t *testing.T, backoff *recordingBackoff, want []int) {
+	t.Helper()
+	backoff.mu.Lock()
+	defer backoff.mu.Unlock()
+	if len(backoff.attempts) != len(want) {
+		t.Fatalf("backoff asked for attempts %v, want %v", backoff.attempts, want)
+	}
+	for i := range want {
+		if backoff.attempts[i] != want[i] {
+			t.Fatalf("backoff asked for attempts %v, want %v", backoff.attempts, want)
+		}
+	}
+}
+
+func TestConnectBacksOffFromTheFirstAttempt(t *testing.T) {
+	backoff := &recordingBackoff{}
+	config := clients.DefaultClientConfig()
+	config.GrpcURL = "127.0.0.1:1"
+	config.MaxRetries = 4
+	config.BackoffJitter = false
+	config.ConnectionTimeout = 50 * time.Millisecond
+	config.Backoff = backoff
+	config.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	_, err := clients.NewCosmosQueryClient(clients.WithConfig(config))
+	if !errors.Is(err, clients.ErrConnectionFailed) {
+		t.Fatalf("NewCosmosQueryClient error = %v, want ErrConnectionFailed", err)
+	}
+
+	// Four attempts sleep between them three times, starting from the first step
+	checkAttempts(t, backoff, []int{1, 2, 3})
+}
+
+func TestQueryRetriesUseQueryBackoff(t *testing.T) {
+	backoff := &recordingBackoff{}
+	server, client := newFakeClient(t,
+		clients.WithQueryRetries(3, time.Hour, time.Hour),
+		clients.WithQueryBackoff(backoff))
+	server.SetSmartQueryHandler(func(string, json.RawMessage) ([]byte, error) {
+		return nil, status.Error(codes.Aborted, "try again")
+	})
+
+	if _, err := client.GetMerkleTreeDataContext(context.Background(), "tree-1"); status.Code(err) != codes.Aborted {
+		t.Fatalf("GetMerkleTreeDataContext error = %v, want Aborted", err)
+	}
+	// The hour-long QueryInitialBackoff is not used once QueryBackoff is set
+	checkAttempts(t, backoff, []int{1, 2, 3})
+}
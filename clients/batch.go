@@ -0,0 +1,150 @@
+package clients
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// BatchError collects the per-ID failures of a batch fetch. Unwrap exposes every
+// underlying error, so errors.Is(err, ErrTreeNotFound) reports whether any ID was
+// missing. NotFound, Unavailable and Other split the failed IDs by cause.
+type BatchError struct {
+	Errors map[string]error
+}
+
+func (e *BatchError) Error() string {
+	ids := e.ids(func(error) bool { return true })
+	parts := make([]string, 0, len(ids))
+	for _, id := range ids {
+		parts = append(parts, fmt.Sprintf("%s: %v", id, e.Errors[id]))
+	}
+	return fmt.Sprintf("%d tree(s) failed: %s", len(ids), strings.Join(parts, "; "))
+}
+
+// NotFound returns the sorted IDs the contract has no tree for
+func (e *BatchError) NotFound() []string {
+	return e.ids(func(err error) bool { return errors.Is(err, ErrTreeNotFound) })
+}
+
+// Unavailable returns the sorted IDs that failed because the node could not be
+// reached or did not answer in time, and are worth retrying later
+func (e *BatchError) Unavailable() []string {
+	return e.ids(isUnavailableError)
+}
+
+// Other returns the sorted IDs that failed for any other reason, such as a tree
+// that could not be decoded or failed validation
+func (e *BatchError) Other() []string {
+	return e.ids(func(err error) bool {
+		return !errors.Is(err, ErrTreeNotFound) && !isUnavailableError(err)
+	})
+}
+
+// ids returns the sorted IDs whose error satisfies match
+func (e *BatchError) ids(match func(error) bool) []string {
+	ids := []string{}
+	for id, err := range e.Errors {
+		if match(err) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// isUnavailableError reports whether err means the query never got an answer:
+// a transport failure, an open circuit, a closed client or a context that ended
+func isUnavailableError(err error) bool {
+	return isTransportError(err) ||
+		errors.Is(err, ErrCircuitOpen) ||
+		errors.Is(err, ErrClientClosed) ||
+		errors.Is(err, context.Canceled) ||
+		errors.Is(err, context.DeadlineExceeded)
+}
+
+func (e *BatchError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Errors))
+	for _, err := range e.Errors {
+		errs = append(errs, err)
+	}
+	return errs
+}
+
+// GetMerkleTreeDataBatch fetches several trees concurrently, using at most
+// BatchConcurrency queries in flight. Trees that were fetched are returned even when
+// others fail; failures are reported per ID through a *BatchError, which tells
+// missing trees apart from ones the node could not be asked for.
+func (cqc *CosmosQueryClient) GetMerkleTreeDataBatch(ctx context.Context, ids []string) (map[string]*MerkleTree, error) {
+	trees := make(map[string]*MerkleTree, len(ids))
+	failures := make(map[string]error)
+	var mu sync.Mutex
+
+	jobs := make(chan string)
+	var wg sync.WaitGroup
+	for i := 0; i < cqc.batchConcurrency(len(ids)); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for id := range jobs {
+				tree, err := cqc.GetMerkleTreeDataContext(ctx, id)
+				mu.Lock()
+				if err != nil {
+					failures[id] = err
+				} else {
+					trees[id] = tree
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	seen := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		if ctx.Err() != nil {
+			mu.Lock()
+			failures[id] = ctx.Err()
+			mu.Unlock()
+			continue
+		}
+		jobs <- id
+	}
+	close(jobs)
+	wg.Wait()
+
+	if len(failures) > 0 {
+		return trees, &BatchError{Errors: failures}
+	}
+	return trees, nil
+}
+
+// batchConcurrency returns the number of workers to use for n items
+func (cqc *CosmosQueryClient) batchConcurrency(n int) int {
+	workers := cqc.config.BatchConcurrency
+	if workers <= 0 {
+		workers = 1
+	}
+	if workers > n {
+		workers = n
+	}
+	return workers
+}
+
+// QueryAllTrees lists every tree ID in the contract and fetches them all, as
+// GetMerkleTreeDataBatch does. Trees that were fetched are returned alongside a
+// *BatchError for the ones that failed; an error listing the IDs returns no trees.
+func (cqc *CosmosQueryClient) QueryAllTrees(ctx context.Context) (map[string]*MerkleTree, error) {
+	ids, err := cqc.ListMerkleTreeIdsContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return cqc.GetMerkleTreeDataBatch(ctx, ids)
+}
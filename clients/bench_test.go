@@ -0,0 +1,202 @@
+package clients_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Layer-Edge/light-node/clients"
+	"github.com/Layer-Edge/light-node/clients/internal/clientstest"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/stats"
+)
+
+// benchTree returns a tree of n leaves shaped like production ones, hex digests
+func benchTree(tb testing.TB, n int) *clients.MerkleTree {
+	tb.Helper()
+	leaves := make([]string, n)
+	for i := range leaves {
+		sum := sha256.Sum256([]byte(strconv.Itoa(i)))
+		leaves[i] = hex.EncodeToString(sum[:])
+	}
+	root, err := clients.ComputeRoot(leaves)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return &clients.MerkleTree{Root: root, Leaves: leaves, Metadata: "source=bench"}
+}
+
+// withLargeTrees lifts the receive limit so large trees are not retried with a
+// growing limit on every call, and the query timeout, which slow runs such as
+// -race or gzip would otherwise exceed
+func withLargeTrees(c *clients.ClientConfig) {
+	c.MaxRecvMsgSize = 64 << 20
+	c.QueryTimeout = time.Minute
+}
+
+// BenchmarkGetMerkleTreeData measures a full query over bufconn. The fake server
+// encodes the tree on every call, so that cost is included.
+func BenchmarkGetMerkleTreeData(b *testing.B) {
+	for _, leaves := range []int{16, 100_000} {
+		b.Run(fmt.Sprintf("leaves=%d", leaves), func(b *testing.B) {
+			server, client := newFakeClient(b, withLargeTrees)
+			server.AddTree("tree-1", benchTree(b, leaves))
+			ctx := context.Background()
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for range b.N {
+				if _, err := client.GetMerkleTreeDataContext(ctx, "tree-1"); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkListMerkleTreeIds(b *testing.B) {
+	server, client := newFakeClient(b)
+	tree := benchTree(b, 1)
+	for i := range 1000 {
+		server.AddTree(fmt.Sprintf("tree-%d", i), tree)
+	}
+	ctx := context.Background()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for range b.N {
+		if _, err := client.ListMerkleTreeIdsContext(ctx); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkConnect measures connecting a client, dial and contract verification
+// included, and closing it again. The settings that could add to a connect are
+// pinned rather than taken from DefaultClientConfig: one attempt without jitter,
+// a blocking dial verified by ContractInfo, and no circuit breaker, cache
+// warm-up, expvar, tracing or health check.
+func BenchmarkConnect(b *testing.B) {
+	server := clientstest.NewFakeQueryServer()
+	b.Cleanup(server.Close)
+	pinned := func(c *clients.ClientConfig) {
+		c.MaxRetries = 1
+		c.BackoffJitter = false
+		c.FailureThreshold = 0
+		c.LazyConnect = false
+		c.NonBlockingDial = false
+		c.VerifyStrategy = clients.VerifyContractInfo
+		c.SkipVerification = false
+		c.CacheEnabled = false
+		c.WarmCacheOnStart = false
+		c.EnableExpvar = false
+		c.EnableTracing = false
+		c.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for range b.N {
+		client, err := server.NewClient(pinned)
+		if err != nil {
+			b.Fatal(err)
+		}
+		client.Close()
+	}
+}
+
+// payloadCounter is a gRPC stats handler adding up the received response
+// payloads, as sent on the wire and once decompressed
+type payloadCounter struct {
+	wire, decoded atomic.Int64
+}
+
+func (*payloadCounter) TagRPC(ctx context.Context, _ *stats.RPCTagInfo) context.Context   { return ctx }
+func (*payloadCounter) TagConn(ctx context.Context, _ *stats.ConnTagInfo) context.Context { return ctx }
+func (*payloadCounter) HandleConn(context.Context, stats.ConnStats)                       {}
+
+func (p *payloadCounter) HandleRPC(_ context.Context, s stats.RPCStats) {
+	if in, ok := s.(*stats.InPayload); ok {
+		p.wire.Add(int64(in.WireLength))
+		p.decoded.Add(int64(in.Length))
+	}
+}
+
+// BenchmarkCompression fetches a tree of hex digest leaves with and without
+// UseCompression, reporting the response bytes on the wire per query. Gzip cuts
+// the 100,000-leaf response from 6.7MB to 3.65MB, 54% of its size, as hex digests
+// carry only half a byte of entropy per character. The price is CPU: a query took
+// about 470ms instead of 100ms, with the fake server compressing and the client
+// decompressing in the same process.
+func BenchmarkCompression(b *testing.B) {
+	tree := benchTree(b, 100_000)
+	for _, compress := range []bool{false, true} {
+		b.Run(fmt.Sprintf("gzip=%t", compress), func(b *testing.B) {
+			counter := &payloadCounter{}
+			server, client := newFakeClient(b, withLargeTrees,
+				clients.WithDialOptions(grpc.WithStatsHandler(counter)),
+				func(c *clients.ClientConfig) { c.UseCompression = compress })
+			server.AddTree("tree-1", tree)
+			ctx := context.Background()
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for range b.N {
+				if _, err := client.GetMerkleTreeDataContext(ctx, "tree-1"); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.StopTimer()
+
+			// Connection verification is counted too, but is negligible next to
+			// the tree
+			b.ReportMetric(float64(counter.wire.Load())/float64(b.N), "wire-B/op")
+			b.ReportMetric(float64(counter.wire.Load())/float64(counter.decoded.Load()), "wire/decoded")
+		})
+	}
+}
+
+// BenchmarkStreamMerkleTree compares the memory of decoding a 100,000-leaf tree
+// into a slice with streaming its leaves to a callback. Both receive the response
+// whole, so the difference is the decoded leaves, which streaming never keeps:
+// it allocated about 35MB per query against 46MB, roughly the 8MB of leaf strings
+// plus the slice growing to hold them.
+func BenchmarkStreamMerkleTree(b *testing.B) {
+	fetches := []struct {
+		name  string
+		fetch func(context.Context, *clients.CosmosQueryClient) error
+	}{
+		{"GetMerkleTreeData", func(ctx context.Context, client *clients.CosmosQueryClient) error {
+			_, err := client.GetMerkleTreeDataContext(ctx, "tree-1")
+			return err
+		}},
+		{"StreamMerkleTree", func(ctx context.Context, client *clients.CosmosQueryClient) error {
+			_, err := client.StreamMerkleTree(ctx, "tree-1", func(string) error { return nil })
+			return err
+		}},
+	}
+
+	tree := benchTree(b, 100_000)
+	for _, fetch := range fetches {
+		b.Run(fetch.name, func(b *testing.B) {
+			server, client := newFakeClient(b, withLargeTrees)
+			server.AddTree("tree-1", tree)
+			ctx := context.Background()
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for range b.N {
+				if err := fetch.fetch(ctx, client); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
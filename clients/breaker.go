@@ -0,0 +1,108 @@
+package clients
+
+import (
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type breakerState int
+
+const (
+	breakerClosed breakerState = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+// circuitBreaker fails queries fast after repeated transport failures. It opens
+// after threshold consecutive failures, rejects queries with ErrCircuitOpen for
+// openDuration, then lets a single probe through (half-open) whose outcome either
+// closes the circuit again or re-opens it. The zero value is a closed breaker.
+type circuitBreaker struct {
+	mu       sync.Mutex
+	state    breakerState
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+// allow reports whether a query may proceed. A threshold of zero or less disables
+// the breaker.
+func (cb *circuitBreaker) allow(threshold int, openDuration time.Duration) error {
+	if threshold <= 0 {
+		return nil
+	}
+
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	switch cb.state {
+	case breakerOpen:
+		if time.Since(cb.openedAt) < openDuration {
+			return ErrCircuitOpen
+		}
+		cb.state = breakerHalfOpen
+		cb.probing = true
+		return nil
+	case breakerHalfOpen:
+		if cb.probing {
+			return ErrCircuitOpen
+		}
+		cb.probing = true
+		return nil
+	default:
+		return nil
+	}
+}
+
+// record feeds the outcome of an allowed query back into the breaker
+func (cb *circuitBreaker) record(threshold int, err error, log *slog.Logger) {
+	if threshold <= 0 {
+		return
+	}
+
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	if !isTransportError(err) {
+		if cb.state != breakerClosed {
+			log.Info("Circuit breaker closed")
+		}
+		cb.state = breakerClosed
+		cb.failures = 0
+		cb.probing = false
+		return
+	}
+
+	cb.failures++
+	if cb.state == breakerHalfOpen || cb.failures >= threshold {
+		if cb.state != breakerOpen {
+			log.Warn("Circuit breaker opened", "consecutive_failures", cb.failures)
+		}
+		cb.state = breakerOpen
+		cb.openedAt = time.Now()
+		cb.probing = false
+	}
+}
+
+// isTransportError reports whether err means the node could not be reached, as
+// opposed to the contract answering with an error of its own
+func isTransportError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, ErrNotConnected) || errors.Is(err, ErrReconnecting) {
+		return true
+	}
+
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.Aborted:
+		return true
+	default:
+		return false
+	}
+}
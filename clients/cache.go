@@ -0,0 +1,267 @@
+package clients
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// CacheStats reports how effective the tree cache is
+type CacheStats struct {
+	Hits    uint64
+	Misses  uint64
+	Stale   uint64 // Hits served from expired entries, see CacheStaleWhileRevalidate
+	Entries int
+}
+
+type cacheEntry struct {
+	id        string
+	tree      *MerkleTree
+	expiresAt time.Time
+}
+
+// missingEntry remembers that the contract reported a tree ID as not found
+type missingEntry struct {
+	err       error
+	expiresAt time.Time
+}
+
+// treeCache is an LRU cache of merkle trees keyed by ID whose entries also expire
+// after a TTL
+type treeCache struct {
+	mu         sync.Mutex
+	ttl        time.Duration
+	serveStale bool // Serve expired entries instead of dropping them
+	maxEntries int
+	order      *list.List // Front is most recently used
+	entries    map[string]*list.Element
+	missingTTL time.Duration
+	missing    map[string]missingEntry // Tree IDs recently reported not found
+	refreshing map[string]bool         // Tree IDs with a background refresh running
+	hits       atomic.Uint64
+	misses     atomic.Uint64
+	stale      atomic.Uint64
+}
+
+func newTreeCache(ttl time.Duration, serveStale bool, maxEntries int, missingTTL time.Duration) *treeCache {
+	return &treeCache{
+		ttl:        ttl,
+		serveStale: serveStale,
+		maxEntries: maxEntries,
+		order:      list.New(),
+		entries:    make(map[string]*list.Element),
+		missingTTL: missingTTL,
+		missing:    make(map[string]missingEntry),
+		refreshing: make(map[string]bool),
+	}
+}
+
+// get returns a copy of the cached tree for id, if present and not expired. When
+// the cache serves stale entries an expired tree is returned too, with stale set.
+func (c *treeCache) get(id string) (tree *MerkleTree, stale bool, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[id]
+	if !ok {
+		c.misses.Add(1)
+		return nil, false, false
+	}
+
+	entry := elem.Value.(*cacheEntry)
+	if c.ttl > 0 && time.Now().After(entry.expiresAt) {
+		if !c.serveStale {
+			c.removeElement(elem)
+			c.misses.Add(1)
+			return nil, false, false
+		}
+		stale = true
+		c.stale.Add(1)
+	}
+
+	c.order.MoveToFront(elem)
+	c.hits.Add(1)
+	return entry.tree.clone(), stale, true
+}
+
+// beginRefresh reports whether the caller should refresh id in the background,
+// which is the case unless another refresh of it is already running. Callers that
+// get true must call endRefresh.
+func (c *treeCache) beginRefresh(id string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.refreshing[id] {
+		return false
+	}
+	c.refreshing[id] = true
+	return true
+}
+
+func (c *treeCache) endRefresh(id string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.refreshing, id)
+}
+
+// put stores a copy of tree, evicting the least recently used entry when full
+func (c *treeCache) put(id string, tree *MerkleTree) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.missing, id)
+	entry := &cacheEntry{id: id, tree: tree.clone(), expiresAt: time.Now().Add(c.ttl)}
+	if elem, ok := c.entries[id]; ok {
+		elem.Value = entry
+		c.order.MoveToFront(elem)
+		return
+	}
+
+	c.entries[id] = c.order.PushFront(entry)
+	for c.maxEntries > 0 && c.order.Len() > c.maxEntries {
+		c.removeElement(c.order.Back())
+	}
+}
+
+// getMissing returns the not-found error recorded for id, or nil if there is none
+// or it has expired
+func (c *treeCache) getMissing(id string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.missing[id]
+	if !ok {
+		return nil
+	}
+	if time.Now().After(entry.expiresAt) {
+		delete(c.missing, id)
+		return nil
+	}
+	c.hits.Add(1)
+	return entry.err
+}
+
+// putMissing records that id was not found, so lookups fail fast until the
+// negative TTL expires, and drops any stale tree cached for it. The number of
+// remembered IDs is bounded like the trees.
+func (c *treeCache) putMissing(id string, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.entries[id]; ok {
+		c.removeElement(elem)
+	}
+	if c.missingTTL <= 0 {
+		return
+	}
+
+	now := time.Now()
+	if c.maxEntries > 0 && len(c.missing) >= c.maxEntries {
+		for missingID, entry := range c.missing {
+			if now.After(entry.expiresAt) {
+				delete(c.missing, missingID)
+			}
+		}
+		if len(c.missing) >= c.maxEntries {
+			return
+		}
+	}
+	c.missing[id] = missingEntry{err: err, expiresAt: now.Add(c.missingTTL)}
+}
+
+func (c *treeCache) invalidate(id string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.missing, id)
+	if elem, ok := c.entries[id]; ok {
+		c.removeElement(elem)
+	}
+}
+
+// clear drops every cached tree and not-found result, keeping the counters
+func (c *treeCache) clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.order.Init()
+	clear(c.entries)
+	clear(c.missing)
+}
+
+func (c *treeCache) removeElement(elem *list.Element) {
+	c.order.Remove(elem)
+	delete(c.entries, elem.Value.(*cacheEntry).id)
+}
+
+func (c *treeCache) stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return CacheStats{
+		Hits:    c.hits.Load(),
+		Misses:  c.misses.Load(),
+		Stale:   c.stale.Load(),
+		Entries: c.order.Len(),
+	}
+}
+
+// treeCache returns the client's cache, or nil when caching is disabled
+func (cqc *CosmosQueryClient) treeCache() *treeCache {
+	if !cqc.config.CacheEnabled {
+		return nil
+	}
+	cqc.cacheOnce.Do(func() {
+		cqc.cache = newTreeCache(cqc.config.CacheTTL, cqc.config.CacheStaleWhileRevalidate, cqc.config.CacheMaxEntries, cqc.config.CacheNegativeTTL)
+	})
+	return cqc.cache
+}
+
+// WarmCache lists every tree ID and fetches each tree into the cache, with at most
+// BatchConcurrency fetches in flight, so the first real lookups are served from
+// memory. Failed IDs do not stop the others and are reported together in a
+// *BatchError. Cancelling ctx stops fetches that have not started.
+func (cqc *CosmosQueryClient) WarmCache(ctx context.Context) error {
+	if cqc.treeCache() == nil {
+		return errors.New("cannot warm the cache: caching is disabled")
+	}
+
+	start := time.Now()
+	trees, err := cqc.QueryAllTrees(ctx)
+	if err != nil {
+		cqc.log().Warn("Failed to warm tree cache", "trees", len(trees), "duration", time.Since(start), "error", err)
+		return err
+	}
+	cqc.log().Info("Warmed tree cache", "trees", len(trees), "duration", time.Since(start))
+	return nil
+}
+
+// InvalidateCache drops the cached tree, or not-found result, for id so the next
+// fetch hits the contract
+func (cqc *CosmosQueryClient) InvalidateCache(id string) {
+	if cache := cqc.treeCache(); cache != nil {
+		cache.invalidate(id)
+	}
+}
+
+// CacheStats returns the tree cache hit/miss counters. It is all zeros when
+// caching is disabled.
+func (cqc *CosmosQueryClient) CacheStats() CacheStats {
+	if cache := cqc.treeCache(); cache != nil {
+		return cache.stats()
+	}
+	return CacheStats{}
+}
+
+// clone returns a deep copy of the tree so cached values cannot be mutated by callers
+func (t *MerkleTree) clone() *MerkleTree {
+	c := *t
+	if t.Leaves != nil {
+		c.Leaves = append([]string(nil), t.Leaves...)
+	}
+	return &c
+}
@@ -0,0 +1,52 @@
+package clientstest_test
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/Layer-Edge/light-node/clients"
+	"github.com/Layer-Edge/light-node/clients/clientstest"
+)
+
+// countLeaves stands in for code under test that takes any clients.QueryClient
+func countLeaves(q clients.QueryClient, id string) (int, error) {
+	tree, err := q.GetMerkleTreeData(id)
+	if err != nil {
+		return 0, err
+	}
+	return len(tree.Leaves), nil
+}
+
+func ExampleFakeQueryClient() {
+	fake := clientstest.NewFakeQueryClient()
+	fake.AddTree("tree-1", &clients.MerkleTree{Root: "ab", Leaves: []string{"apple", "banana"}})
+
+	n, err := countLeaves(fake, "tree-1")
+	fmt.Println(n, err)
+
+	ids, _ := fake.ListMerkleTreeIds()
+	fmt.Println(ids)
+	// Output:
+	// 2 <nil>
+	// [tree-1]
+}
+
+func ExampleFakeQueryClient_SetError() {
+	fake := clientstest.NewFakeQueryClient()
+	fake.AddTree("tree-1", &clients.MerkleTree{Root: "ab", Leaves: []string{"apple"}})
+
+	// Script the tree as missing even though it is stored, to exercise the
+	// caller's not-found path
+	fake.SetError("tree-1", fmt.Errorf("%w: tree-1", clients.ErrTreeNotFound))
+
+	_, err := countLeaves(fake, "tree-1")
+	fmt.Println(errors.Is(err, clients.ErrTreeNotFound))
+
+	// Clearing the error serves the stored tree again
+	fake.SetError("tree-1", nil)
+	n, err := countLeaves(fake, "tree-1")
+	fmt.Println(n, err)
+	// Output:
+	// true
+	// 1 <nil>
+}
@@ -0,0 +1,165 @@
+// Package clientstest provides an in-memory clients.QueryClient for tests.
+//
+// A test seeds the fake with trees and scripts failures, then hands it to the code
+// under test in place of a live CosmosQueryClient:
+//
+//	fake := clientstest.NewFakeQueryClient()
+//	fake.AddTree("tree-1", &clients.MerkleTree{Root: root, Leaves: leaves})
+//	fake.SetError("tree-2", clients.ErrTreeNotFound)
+//	fake.SetLatency(50 * time.Millisecond)
+//
+// IDs that were never added return an error wrapping clients.ErrTreeNotFound, just
+// like the real client.
+package clientstest
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Layer-Edge/light-node/clients"
+)
+
+// FakeQueryClient serves merkle trees from memory. It is safe for concurrent use.
+type FakeQueryClient struct {
+	mu      sync.Mutex
+	trees   map[string]*clients.MerkleTree
+	errs    map[string]error
+	listErr error
+	latency time.Duration
+	closed  bool
+}
+
+var _ clients.QueryClient = (*FakeQueryClient)(nil)
+
+// NewFakeQueryClient returns an empty fake
+func NewFakeQueryClient() *FakeQueryClient {
+	return &FakeQueryClient{
+		trees: make(map[string]*clients.MerkleTree),
+		errs:  make(map[string]error),
+	}
+}
+
+// AddTree stores a copy of tree under id, replacing any existing tree
+func (f *FakeQueryClient) AddTree(id string, tree *clients.MerkleTree) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.trees[id] = cloneTree(tree)
+}
+
+// RemoveTree deletes the tree stored under id
+func (f *FakeQueryClient) RemoveTree(id string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.trees, id)
+}
+
+// SetError makes GetMerkleTreeData fail with err for id. A nil err clears it.
+func (f *FakeQueryClient) SetError(id string, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err == nil {
+		delete(f.errs, id)
+		return
+	}
+	f.errs[id] = err
+}
+
+// SetListError makes ListMerkleTreeIds fail with err. A nil err clears it.
+func (f *FakeQueryClient) SetListError(err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.listErr = err
+}
+
+// SetLatency delays every query by d, or until the query's context is done
+func (f *FakeQueryClient) SetLatency(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.latency = d
+}
+
+// Closed reports whether Close has been called
+func (f *FakeQueryClient) Closed() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.closed
+}
+
+func (f *FakeQueryClient) GetMerkleTreeData(id string) (*clients.MerkleTree, error) {
+	return f.GetMerkleTreeDataContext(context.Background(), id)
+}
+
+func (f *FakeQueryClient) GetMerkleTreeDataContext(ctx context.Context, id string) (*clients.MerkleTree, error) {
+	if err := f.wait(ctx); err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err, ok := f.errs[id]; ok {
+		return nil, err
+	}
+	tree, ok := f.trees[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", clients.ErrTreeNotFound, id)
+	}
+	return cloneTree(tree), nil
+}
+
+func (f *FakeQueryClient) ListMerkleTreeIds() ([]string, error) {
+	return f.ListMerkleTreeIdsContext(context.Background())
+}
+
+// ListMerkleTreeIdsContext returns the stored IDs in sorted order
+func (f *FakeQueryClient) ListMerkleTreeIdsContext(ctx context.Context) ([]string, error) {
+	if err := f.wait(ctx); err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.listErr != nil {
+		return nil, f.listErr
+	}
+	ids := make([]string, 0, len(f.trees))
+	for id := range f.trees {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+func (f *FakeQueryClient) Close() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.closed = true
+}
+
+// wait applies the configured latency
+func (f *FakeQueryClient) wait(ctx context.Context) error {
+	f.mu.Lock()
+	latency := f.latency
+	f.mu.Unlock()
+
+	if latency <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(latency)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+func cloneTree(tree *clients.MerkleTree) *clients.MerkleTree {
+	c := *tree
+	c.Leaves = append([]string(nil), tree.Leaves...)
+	return &c
+}
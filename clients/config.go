@@ -0,0 +1,242 @@
+package clients
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+
+	"github.com/cosmos/cosmos-sdk/types/bech32"
+)
+
+// VerifyStrategy selects how a freshly dialed connection is checked before use
+type VerifyStrategy int
+
+const (
+	// VerifyContractInfo queries ContractInfo for ContractAddr, so a wrong contract
+	// address keeps the client from connecting. This is the default.
+	VerifyContractInfo VerifyStrategy = iota
+	// VerifyConnectivity only requires the connection to become Ready, leaving
+	// contract problems to surface on the first real query
+	VerifyConnectivity
+	// VerifySmartQuery sends VerifyQuery as a smart query to ContractAddr
+	VerifySmartQuery
+)
+
+// ParseVerifyStrategy returns the strategy for "contract_info", "connectivity" or
+// "smart_query"
+func ParseVerifyStrategy(name string) (VerifyStrategy, error) {
+	switch strings.ToLower(strings.ReplaceAll(name, "-", "_")) {
+	case "contract_info":
+		return VerifyContractInfo, nil
+	case "connectivity":
+		return VerifyConnectivity, nil
+	case "smart_query":
+		return VerifySmartQuery, nil
+	default:
+		return 0, fmt.Errorf("unknown verify strategy %q, expected contract_info, connectivity or smart_query", name)
+	}
+}
+
+// String returns the name ParseVerifyStrategy accepts for s
+func (s VerifyStrategy) String() string {
+	switch s {
+	case VerifyContractInfo:
+		return "contract_info"
+	case VerifyConnectivity:
+		return "connectivity"
+	case VerifySmartQuery:
+		return "smart_query"
+	default:
+		return fmt.Sprintf("VerifyStrategy(%d)", int(s))
+	}
+}
+
+// Validate checks the configuration for values that would otherwise only show up
+// as confusing failures once the client starts dialing. All problems found are
+// reported together, wrapped in ErrInvalidConfig.
+func (c ClientConfig) Validate() error {
+	var problems []error
+
+	for _, endpoint := range c.Endpoints() {
+		if err := validateEndpoint(endpoint); err != nil {
+			problems = append(problems, err)
+		}
+	}
+
+	if c.ServiceConfig != "" && !json.Valid([]byte(c.ServiceConfig)) {
+		problems = append(problems, fmt.Errorf("service config is not valid JSON: %q", c.ServiceConfig))
+	}
+
+	if err := validateContractAddr(c.ContractAddr); err != nil {
+		problems = append(problems, err)
+	}
+
+	if c.MaxRetries < -1 {
+		problems = append(problems, fmt.Errorf("max retries must be -1 (unlimited) or more, got %d", c.MaxRetries))
+	}
+	if c.QueryMaxRetries < 0 {
+		problems = append(problems, fmt.Errorf("query max retries must not be negative, got %d", c.QueryMaxRetries))
+	}
+	if c.InitialBackoff < 0 || c.MaxBackoff < 0 {
+		problems = append(problems, fmt.Errorf("backoffs must not be negative, got initial %v and max %v", c.InitialBackoff, c.MaxBackoff))
+	} else if c.InitialBackoff > c.MaxBackoff {
+		problems = append(problems, fmt.Errorf("initial backoff %v exceeds max backoff %v", c.InitialBackoff, c.MaxBackoff))
+	}
+	if c.QueryInitialBackoff < 0 || c.QueryMaxBackoff < 0 {
+		problems = append(problems, fmt.Errorf("query backoffs must not be negative, got initial %v and max %v", c.QueryInitialBackoff, c.QueryMaxBackoff))
+	} else if c.QueryInitialBackoff > c.QueryMaxBackoff {
+		problems = append(problems, fmt.Errorf("query initial backoff %v exceeds query max backoff %v", c.QueryInitialBackoff, c.QueryMaxBackoff))
+	}
+
+	switch c.VerifyStrategy {
+	case VerifyContractInfo, VerifyConnectivity:
+	case VerifySmartQuery:
+		if !c.SkipVerification && !json.Valid(c.VerifyQuery) {
+			problems = append(problems, fmt.Errorf("verify query must be valid JSON for the smart query strategy, got %q", c.VerifyQuery))
+		}
+	default:
+		problems = append(problems, fmt.Errorf("unknown verify strategy %d", c.VerifyStrategy))
+	}
+
+	if c.MaxElapsedTime < 0 || c.BackoffResetAfter < 0 {
+		problems = append(problems, fmt.Errorf("max elapsed time and backoff reset must not be negative, got %v and %v", c.MaxElapsedTime, c.BackoffResetAfter))
+	}
+
+	if c.ConnectionTimeout <= 0 {
+		problems = append(problems, fmt.Errorf("connection timeout must be positive, got %v", c.ConnectionTimeout))
+	}
+	if c.QueryTimeout < 0 {
+		problems = append(problems, fmt.Errorf("query timeout must not be negative, got %v", c.QueryTimeout))
+	}
+	if c.KeepaliveTime < 0 || c.KeepaliveTimeout < 0 {
+		problems = append(problems, fmt.Errorf("keepalive durations must not be negative, got time %v and timeout %v", c.KeepaliveTime, c.KeepaliveTimeout))
+	}
+	if c.IdleTimeout < 0 {
+		problems = append(problems, fmt.Errorf("idle timeout must not be negative, got %v", c.IdleTimeout))
+	}
+	if c.HealthCheckInterval < 0 {
+		problems = append(problems, fmt.Errorf("health check interval must not be negative, got %v", c.HealthCheckInterval))
+	}
+	if c.UnhealthyThreshold < 0 {
+		problems = append(problems, fmt.Errorf("unhealthy threshold must not be negative, got %v", c.UnhealthyThreshold))
+	}
+	if c.RateLimitQPS < 0 || c.RateLimitBurst < 0 {
+		problems = append(problems, fmt.Errorf("rate limit QPS and burst must not be negative, got %v and %d", c.RateLimitQPS, c.RateLimitBurst))
+	}
+	if c.MaxBlockLag < 0 {
+		problems = append(problems, fmt.Errorf("max block lag must not be negative, got %d", c.MaxBlockLag))
+	}
+	if c.MaxLeaves < 0 {
+		problems = append(problems, fmt.Errorf("max leaves must not be negative, got %d", c.MaxLeaves))
+	}
+	if c.BatchConcurrency < 0 {
+		problems = append(problems, fmt.Errorf("batch concurrency must not be negative, got %d", c.BatchConcurrency))
+	}
+	if c.MaxRecvMsgSize < 0 || c.MaxSendMsgSize < 0 {
+		problems = append(problems, fmt.Errorf("message size limits must not be negative, got receive %d and send %d", c.MaxRecvMsgSize, c.MaxSendMsgSize))
+	}
+	if c.FailureThreshold > 0 && c.OpenDuration <= 0 {
+		problems = append(problems, fmt.Errorf("circuit breaker open duration must be positive, got %v", c.OpenDuration))
+	}
+
+	if c.CacheTTL < 0 || c.CacheMaxEntries < 0 {
+		problems = append(problems, fmt.Errorf("cache TTL and max entries must not be negative, got %v and %d", c.CacheTTL, c.CacheMaxEntries))
+	}
+	if c.WarmCacheOnStart && !c.CacheEnabled {
+		problems = append(problems, errors.New("warming the cache on start requires the cache to be enabled"))
+	}
+	if c.CacheNegativeTTL < 0 {
+		problems = append(problems, fmt.Errorf("negative cache TTL must not be negative, got %v", c.CacheNegativeTTL))
+	}
+
+	if c.LCDURL != "" {
+		if u, err := url.Parse(c.LCDURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			problems = append(problems, fmt.Errorf("LCD URL %q must be an http or https URL", c.LCDURL))
+		}
+	} else if c.LCDFallback {
+		problems = append(problems, errors.New("LCD fallback is enabled but no LCD URL is set"))
+	}
+
+	if (c.TLSClientCertPath == "") != (c.TLSClientKeyPath == "") {
+		problems = append(problems, errors.New("TLS client certificate and key must be set together"))
+	}
+
+	if len(problems) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%w: %w", ErrInvalidConfig, errors.Join(problems...))
+}
+
+// redacted replaces a secret-bearing value so that only its presence is shown
+func redacted(value string) string {
+	if value == "" {
+		return ""
+	}
+	return "<redacted>"
+}
+
+// String describes the configuration for logging. The auth token and TLS client
+// key path are redacted; everything else is shown as is.
+func (c ClientConfig) String() string {
+	return fmt.Sprintf("ClientConfig{Endpoints: %v, ContractAddr: %s, MaxRetries: %d, InitialBackoff: %v, MaxBackoff: %v, "+
+		"QueryMaxRetries: %d, ConnectionTimeout: %v, QueryTimeout: %v, UseTLS: %t, TLSServerName: %q, TLSCAPath: %q, "+
+		"TLSClientCertPath: %q, TLSClientKeyPath: %q, AuthToken: %q, VerifyRoot: %t, MerkleHasher: %T, CacheEnabled: %t}",
+		c.Endpoints(), c.ContractAddr, c.MaxRetries, c.InitialBackoff, c.MaxBackoff,
+		c.QueryMaxRetries, c.ConnectionTimeout, c.QueryTimeout, c.UseTLS, c.TLSServerName, c.TLSCAPath,
+		c.TLSClientCertPath, redacted(c.TLSClientKeyPath), redacted(c.AuthToken), c.VerifyRoot, c.Merkle.hasher(), c.CacheEnabled)
+}
+
+// validateContractAddr checks that a contract address is a bech32 address
+func validateContractAddr(addr string) error {
+	if addr == "" {
+		return errors.New("contract address is empty")
+	}
+	if _, _, err := bech32.DecodeAndConvert(addr); err != nil {
+		return fmt.Errorf("contract address %q is not a valid bech32 address: %v", addr, err)
+	}
+	return nil
+}
+
+// validateEndpoint checks that a gRPC endpoint is a host:port pair with a port,
+// optionally as a dns:///host:port or dns://authority/host:port target, or a UNIX
+// socket given as unix:///absolute/path or unix:relative/path
+func validateEndpoint(endpoint string) error {
+	if endpoint == "" {
+		return errors.New("gRPC endpoint is empty")
+	}
+
+	if path, ok := strings.CutPrefix(endpoint, "unix:"); ok {
+		if strings.HasPrefix(path, "//") && !strings.HasPrefix(path, "///") {
+			return fmt.Errorf("gRPC endpoint %q must be unix:///absolute/path or unix:relative/path", endpoint)
+		}
+		if strings.TrimLeft(path, "/") == "" {
+			return fmt.Errorf("gRPC endpoint %q has no socket path", endpoint)
+		}
+		return nil
+	}
+
+	hostPort := endpoint
+	if rest, ok := strings.CutPrefix(endpoint, "dns:"); ok {
+		// The optional authority names the DNS server to use
+		if after, ok := strings.CutPrefix(rest, "//"); ok {
+			_, target, found := strings.Cut(after, "/")
+			if !found {
+				return fmt.Errorf("gRPC endpoint %q must be dns:///host:port or dns://authority/host:port", endpoint)
+			}
+			rest = target
+		}
+		hostPort = rest
+	}
+
+	host, port, err := net.SplitHostPort(hostPort)
+	if err != nil {
+		return fmt.Errorf("gRPC endpoint %q is not host:port: %v", endpoint, err)
+	}
+	if host == "" || port == "" {
+		return fmt.Errorf("gRPC endpoint %q must include both host and port", endpoint)
+	}
+	return nil
+}
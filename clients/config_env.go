@@ -0,0 +1,210 @@
+package clients
+
+import (
+	"encoding/hex"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/joho/godotenv"
+)
+
+// loadDotEnv loads the .env file into the environment, once per process rather
+// than on every lookup as utils.GetEnv does, so reading the configuration neither
+// rereads the file nor repeats the warning for each variable. Variables already
+// set in the environment take precedence over the file.
+var loadDotEnv = sync.OnceFunc(func() {
+	if err := godotenv.Load(); err != nil {
+		logger.Warn(".env file not loaded", "error", err)
+	}
+})
+
+// getEnv returns the environment variable key, or defaultValue when it is unset
+func getEnv(key, defaultValue string) string {
+	loadDotEnv()
+	if value, ok := os.LookupEnv(key); ok {
+		return value
+	}
+	return defaultValue
+}
+
+// splitList splits a comma-separated list, dropping empty entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// getEnvList reads a comma-separated list from the environment, keeping the
+// default when the variable is unset or empty
+func getEnvList(key string, defaultValue []string) []string {
+	value := getEnv(key, "")
+	if value == "" {
+		return defaultValue
+	}
+	return splitList(value)
+}
+
+// getEnvDuration reads a duration such as "30s" from the environment, keeping the
+// default when the variable is unset or cannot be parsed
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	value := getEnv(key, "")
+	if value == "" {
+		return defaultValue
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		logger.Warn("Ignoring unparseable environment variable", "key", key, "value", value,
+			"default", defaultValue, "error", err)
+		return defaultValue
+	}
+	return d
+}
+
+// getEnvInt reads an integer from the environment, keeping the default when the
+// variable is unset or cannot be parsed
+func getEnvInt(key string, defaultValue int) int {
+	value := getEnv(key, "")
+	if value == "" {
+		return defaultValue
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		logger.Warn("Ignoring unparseable environment variable", "key", key, "value", value,
+			"default", defaultValue, "error", err)
+		return defaultValue
+	}
+	return n
+}
+
+// getEnvFloat reads a number such as "2.5" from the environment, keeping the
+// default when the variable is unset or cannot be parsed
+func getEnvFloat(key string, defaultValue float64) float64 {
+	value := getEnv(key, "")
+	if value == "" {
+		return defaultValue
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		logger.Warn("Ignoring unparseable environment variable", "key", key, "value", value,
+			"default", defaultValue, "error", err)
+		return defaultValue
+	}
+	return f
+}
+
+// getEnvBool reads a boolean such as "true" or "0" from the environment, keeping
+// the default when the variable is unset or cannot be parsed
+func getEnvBool(key string, defaultValue bool) bool {
+	value := getEnv(key, "")
+	if value == "" {
+		return defaultValue
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		logger.Warn("Ignoring unparseable environment variable", "key", key, "value", value,
+			"default", defaultValue, "error", err)
+		return defaultValue
+	}
+	return b
+}
+
+// getEnvHex reads hex-encoded bytes from the environment, keeping the default when
+// the variable is unset or cannot be parsed
+func getEnvHex(key string, defaultValue []byte) []byte {
+	value := getEnv(key, "")
+	if value == "" {
+		return defaultValue
+	}
+	b, err := hex.DecodeString(strings.TrimPrefix(value, "0x"))
+	if err != nil {
+		logger.Warn("Ignoring unparseable environment variable", "key", key, "value", value,
+			"default", hex.EncodeToString(defaultValue), "error", err)
+		return defaultValue
+	}
+	return b
+}
+
+// ToEnv returns the environment variables that make ClientConfigFromEnv rebuild
+// c, e.g. to pass c on to a child process. Every field that has a variable is
+// written, including zero values, so the result does not depend on the child's
+// defaults. Fields that cannot be written as text are left out: Backoff,
+// QueryBackoff, MetadataFunc, DialOptions, Interceptors, TracerProvider,
+// ReferenceHeight, Logger, and a Merkle.Hasher other than the built-in
+// SHA256Hasher and Keccak256Hasher. Secrets such as AuthToken are included as
+// they are.
+func (c ClientConfig) ToEnv() map[string]string {
+	env := map[string]string{
+		"GRPC_URL":                     c.GrpcURL,
+		"GRPC_URLS":                    strings.Join(c.GrpcURLs, ","),
+		"CONTRACT_ADDR":                c.ContractAddr,
+		"GRPC_SERVICE_CONFIG":          c.ServiceConfig,
+		"MAX_RETRIES":                  strconv.Itoa(c.MaxRetries),
+		"INITIAL_BACKOFF":              c.InitialBackoff.String(),
+		"MAX_BACKOFF":                  c.MaxBackoff.String(),
+		"BACKOFF_JITTER":               strconv.FormatBool(c.BackoffJitter),
+		"MAX_ELAPSED_TIME":             c.MaxElapsedTime.String(),
+		"BACKOFF_RESET_AFTER":          c.BackoffResetAfter.String(),
+		"QUERY_MAX_RETRIES":            strconv.Itoa(c.QueryMaxRetries),
+		"QUERY_INITIAL_BACKOFF":        c.QueryInitialBackoff.String(),
+		"QUERY_MAX_BACKOFF":            c.QueryMaxBackoff.String(),
+		"QUERY_BACKOFF_JITTER":         strconv.FormatBool(c.QueryBackoffJitter),
+		"FAILURE_THRESHOLD":            strconv.Itoa(c.FailureThreshold),
+		"OPEN_DURATION":                c.OpenDuration.String(),
+		"CONNECTION_TIMEOUT":           c.ConnectionTimeout.String(),
+		"LAZY_CONNECT":                 strconv.FormatBool(c.LazyConnect),
+		"GRPC_NON_BLOCKING_DIAL":       strconv.FormatBool(c.NonBlockingDial),
+		"VERIFY_STRATEGY":              c.VerifyStrategy.String(),
+		"VERIFY_QUERY":                 string(c.VerifyQuery),
+		"SKIP_VERIFICATION":            strconv.FormatBool(c.SkipVerification),
+		"GRPC_TLS":                     strconv.FormatBool(c.UseTLS),
+		"GRPC_TLS_SERVER_NAME":         c.TLSServerName,
+		"GRPC_TLS_CA":                  c.TLSCAPath,
+		"GRPC_TLS_CERT":                c.TLSClientCertPath,
+		"GRPC_TLS_KEY":                 c.TLSClientKeyPath,
+		"GRPC_AUTH_TOKEN":              c.AuthToken,
+		"LCD_URL":                      c.LCDURL,
+		"LCD_FALLBACK":                 strconv.FormatBool(c.LCDFallback),
+		"GRPC_REQUEST_ID":              strconv.FormatBool(c.SendRequestID),
+		"QUERY_TIMEOUT":                c.QueryTimeout.String(),
+		"GRPC_WAIT_FOR_READY":          strconv.FormatBool(c.WaitForReady),
+		"GRPC_KEEPALIVE_TIME":          c.KeepaliveTime.String(),
+		"GRPC_KEEPALIVE_TIMEOUT":       c.KeepaliveTimeout.String(),
+		"GRPC_IDLE_TIMEOUT":            c.IdleTimeout.String(),
+		"GRPC_MAX_RECV_MSG_SIZE":       strconv.Itoa(c.MaxRecvMsgSize),
+		"GRPC_MAX_SEND_MSG_SIZE":       strconv.Itoa(c.MaxSendMsgSize),
+		"GRPC_COMPRESSION":             strconv.FormatBool(c.UseCompression),
+		"HEALTH_CHECK_INTERVAL":        c.HealthCheckInterval.String(),
+		"UNHEALTHY_THRESHOLD":          c.UnhealthyThreshold.String(),
+		"ENABLE_EXPVAR":                strconv.FormatBool(c.EnableExpvar),
+		"ENABLE_TRACING":               strconv.FormatBool(c.EnableTracing),
+		"VERIFY_ROOT":                  strconv.FormatBool(c.VerifyRoot),
+		"STRICT_LEAVES":                strconv.FormatBool(c.StrictLeaves),
+		"DEDUP_LEAVES":                 strconv.FormatBool(c.DedupLeaves),
+		"MAX_LEAVES":                   strconv.Itoa(c.MaxLeaves),
+		"MAX_BLOCK_LAG":                strconv.Itoa(c.MaxBlockLag),
+		"MERKLE_LEAF_PREFIX":           hex.EncodeToString(c.Merkle.LeafPrefix),
+		"MERKLE_NODE_PREFIX":           hex.EncodeToString(c.Merkle.NodePrefix),
+		"MERKLE_SORT_PAIRS":            strconv.FormatBool(c.Merkle.SortPairs),
+		"MERKLE_OPENZEPPELIN":          strconv.FormatBool(c.Merkle.OpenZeppelin),
+		"RATE_LIMIT_QPS":               strconv.FormatFloat(c.RateLimitQPS, 'g', -1, 64),
+		"RATE_LIMIT_BURST":             strconv.Itoa(c.RateLimitBurst),
+		"BATCH_CONCURRENCY":            strconv.Itoa(c.BatchConcurrency),
+		"CACHE_ENABLED":                strconv.FormatBool(c.CacheEnabled),
+		"CACHE_STALE_WHILE_REVALIDATE": strconv.FormatBool(c.CacheStaleWhileRevalidate),
+		"CACHE_WARM_ON_START":          strconv.FormatBool(c.WarmCacheOnStart),
+		"CACHE_TTL":                    c.CacheTTL.String(),
+		"CACHE_MAX_ENTRIES":            strconv.Itoa(c.CacheMaxEntries),
+		"CACHE_NEGATIVE_TTL":           c.CacheNegativeTTL.String(),
+	}
+	if name, ok := hasherName(c.Merkle.Hasher); ok {
+		env["MERKLE_HASH"] = name
+	}
+	return env
+}
@@ -0,0 +1,245 @@
+package clients
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// configDuration is a time.Duration written as a string such as "30s" in config files
+type configDuration time.Duration
+
+func (d *configDuration) set(value string) error {
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		return err
+	}
+	*d = configDuration(parsed)
+	return nil
+}
+
+func (d *configDuration) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return fmt.Errorf("duration must be a string like \"30s\": %v", err)
+	}
+	return d.set(value)
+}
+
+func (d *configDuration) UnmarshalYAML(node *yaml.Node) error {
+	return d.set(node.Value)
+}
+
+// fileClientConfig mirrors ClientConfig as it appears in a config file. Every field
+// is a pointer so that keys missing from the file keep their default value.
+type fileClientConfig struct {
+	GrpcURL             *string         `json:"grpc_url" yaml:"grpc_url"`
+	GrpcURLs            []string        `json:"grpc_urls" yaml:"grpc_urls"`
+	ContractAddr        *string         `json:"contract_addr" yaml:"contract_addr"`
+	ServiceConfig       *string         `json:"service_config" yaml:"service_config"`
+	MaxRetries          *int            `json:"max_retries" yaml:"max_retries"`
+	InitialBackoff      *configDuration `json:"initial_backoff" yaml:"initial_backoff"`
+	MaxBackoff          *configDuration `json:"max_backoff" yaml:"max_backoff"`
+	BackoffJitter       *bool           `json:"backoff_jitter" yaml:"backoff_jitter"`
+	MaxElapsedTime      *configDuration `json:"max_elapsed_time" yaml:"max_elapsed_time"`
+	BackoffResetAfter   *configDuration `json:"backoff_reset_after" yaml:"backoff_reset_after"`
+	QueryMaxRetries     *int            `json:"query_max_retries" yaml:"query_max_retries"`
+	QueryInitialBackoff *configDuration `json:"query_initial_backoff" yaml:"query_initial_backoff"`
+	QueryMaxBackoff     *configDuration `json:"query_max_backoff" yaml:"query_max_backoff"`
+	QueryBackoffJitter  *bool           `json:"query_backoff_jitter" yaml:"query_backoff_jitter"`
+	FailureThreshold    *int            `json:"failure_threshold" yaml:"failure_threshold"`
+	OpenDuration        *configDuration `json:"open_duration" yaml:"open_duration"`
+	ConnectionTimeout   *configDuration `json:"connection_timeout" yaml:"connection_timeout"`
+	LazyConnect         *bool           `json:"lazy_connect" yaml:"lazy_connect"`
+	NonBlockingDial     *bool           `json:"non_blocking_dial" yaml:"non_blocking_dial"`
+	VerifyStrategy      *string         `json:"verify_strategy" yaml:"verify_strategy"`
+	VerifyQuery         *string         `json:"verify_query" yaml:"verify_query"`
+	SkipVerification    *bool           `json:"skip_verification" yaml:"skip_verification"`
+	QueryTimeout        *configDuration `json:"query_timeout" yaml:"query_timeout"`
+	WaitForReady        *bool           `json:"wait_for_ready" yaml:"wait_for_ready"`
+	UseTLS              *bool           `json:"use_tls" yaml:"use_tls"`
+	TLSServerName       *string         `json:"tls_server_name" yaml:"tls_server_name"`
+	TLSCAPath           *string         `json:"tls_ca_path" yaml:"tls_ca_path"`
+	TLSClientCertPath   *string         `json:"tls_client_cert_path" yaml:"tls_client_cert_path"`
+	TLSClientKeyPath    *string         `json:"tls_client_key_path" yaml:"tls_client_key_path"`
+	AuthToken           *string         `json:"auth_token" yaml:"auth_token"`
+	SendRequestID       *bool           `json:"send_request_id" yaml:"send_request_id"`
+	LCDURL              *string         `json:"lcd_url" yaml:"lcd_url"`
+	LCDFallback         *bool           `json:"lcd_fallback" yaml:"lcd_fallback"`
+	KeepaliveTime       *configDuration `json:"keepalive_time" yaml:"keepalive_time"`
+	KeepaliveTimeout    *configDuration `json:"keepalive_timeout" yaml:"keepalive_timeout"`
+	IdleTimeout         *configDuration `json:"idle_timeout" yaml:"idle_timeout"`
+	MaxRecvMsgSize      *int            `json:"max_recv_msg_size" yaml:"max_recv_msg_size"`
+	MaxSendMsgSize      *int            `json:"max_send_msg_size" yaml:"max_send_msg_size"`
+	UseCompression      *bool           `json:"use_compression" yaml:"use_compression"`
+	VerifyRoot          *bool           `json:"verify_root" yaml:"verify_root"`
+	StrictLeaves        *bool           `json:"strict_leaves" yaml:"strict_leaves"`
+	DedupLeaves         *bool           `json:"dedup_leaves" yaml:"dedup_leaves"`
+	MaxLeaves           *int            `json:"max_leaves" yaml:"max_leaves"`
+	MaxBlockLag         *int            `json:"max_block_lag" yaml:"max_block_lag"`
+	MerkleHash          *string         `json:"merkle_hash" yaml:"merkle_hash"`
+	MerkleSortPairs     *bool           `json:"merkle_sort_pairs" yaml:"merkle_sort_pairs"`
+	MerkleOpenZeppelin  *bool           `json:"merkle_openzeppelin" yaml:"merkle_openzeppelin"`
+	CacheEnabled        *bool           `json:"cache_enabled" yaml:"cache_enabled"`
+	CacheTTL            *configDuration `json:"cache_ttl" yaml:"cache_ttl"`
+	CacheMaxEntries     *int            `json:"cache_max_entries" yaml:"cache_max_entries"`
+	WarmCacheOnStart    *bool           `json:"cache_warm_on_start" yaml:"cache_warm_on_start"`
+	CacheStale          *bool           `json:"cache_stale_while_revalidate" yaml:"cache_stale_while_revalidate"`
+	CacheNegativeTTL    *configDuration `json:"cache_negative_ttl" yaml:"cache_negative_ttl"`
+	RateLimitQPS        *float64        `json:"rate_limit_qps" yaml:"rate_limit_qps"`
+	RateLimitBurst      *int            `json:"rate_limit_burst" yaml:"rate_limit_burst"`
+	BatchConcurrency    *int            `json:"batch_concurrency" yaml:"batch_concurrency"`
+	EnableExpvar        *bool           `json:"enable_expvar" yaml:"enable_expvar"`
+	EnableTracing       *bool           `json:"enable_tracing" yaml:"enable_tracing"`
+	HealthCheckInterval *configDuration `json:"health_check_interval" yaml:"health_check_interval"`
+	UnhealthyThreshold  *configDuration `json:"unhealthy_threshold" yaml:"unhealthy_threshold"`
+}
+
+// LoadClientConfigFromFile reads a client configuration from a JSON file, or a YAML
+// file when the extension is .yaml or .yml. Durations are written as strings such
+// as "30s" or "10m", and keys missing from the file keep their default value.
+func LoadClientConfigFromFile(path string) (ClientConfig, error) {
+	config := DefaultClientConfig()
+	err := loadConfigFile(path, &config)
+	return config, err
+}
+
+// loadConfigFile applies the values present in the file at path onto config
+func loadConfigFile(path string, config *ClientConfig) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read client config %s: %v", path, err)
+	}
+
+	var file fileClientConfig
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &file)
+	default:
+		err = json.Unmarshal(data, &file)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to parse client config %s: %v", path, err)
+	}
+
+	if err := file.apply(config); err != nil {
+		return fmt.Errorf("invalid client config %s: %v", path, err)
+	}
+	return nil
+}
+
+// apply copies every value present in the file onto config
+func (f fileClientConfig) apply(config *ClientConfig) error {
+	setString(&config.GrpcURL, f.GrpcURL)
+	if f.GrpcURLs != nil {
+		config.GrpcURLs = f.GrpcURLs
+	}
+	setString(&config.ContractAddr, f.ContractAddr)
+	setString(&config.ServiceConfig, f.ServiceConfig)
+	setInt(&config.MaxRetries, f.MaxRetries)
+	setDuration(&config.InitialBackoff, f.InitialBackoff)
+	setDuration(&config.MaxBackoff, f.MaxBackoff)
+	setBool(&config.BackoffJitter, f.BackoffJitter)
+	setDuration(&config.MaxElapsedTime, f.MaxElapsedTime)
+	setDuration(&config.BackoffResetAfter, f.BackoffResetAfter)
+	setInt(&config.QueryMaxRetries, f.QueryMaxRetries)
+	setDuration(&config.QueryInitialBackoff, f.QueryInitialBackoff)
+	setDuration(&config.QueryMaxBackoff, f.QueryMaxBackoff)
+	setBool(&config.QueryBackoffJitter, f.QueryBackoffJitter)
+	setInt(&config.FailureThreshold, f.FailureThreshold)
+	setDuration(&config.OpenDuration, f.OpenDuration)
+	setDuration(&config.ConnectionTimeout, f.ConnectionTimeout)
+	setBool(&config.LazyConnect, f.LazyConnect)
+	setBool(&config.NonBlockingDial, f.NonBlockingDial)
+	if f.VerifyStrategy != nil {
+		strategy, err := ParseVerifyStrategy(*f.VerifyStrategy)
+		if err != nil {
+			return err
+		}
+		config.VerifyStrategy = strategy
+	}
+	if f.VerifyQuery != nil {
+		config.VerifyQuery = json.RawMessage(*f.VerifyQuery)
+	}
+	setBool(&config.SkipVerification, f.SkipVerification)
+	setDuration(&config.QueryTimeout, f.QueryTimeout)
+	setBool(&config.WaitForReady, f.WaitForReady)
+	setBool(&config.UseTLS, f.UseTLS)
+	setString(&config.TLSServerName, f.TLSServerName)
+	setString(&config.TLSCAPath, f.TLSCAPath)
+	setString(&config.TLSClientCertPath, f.TLSClientCertPath)
+	setString(&config.TLSClientKeyPath, f.TLSClientKeyPath)
+	setString(&config.AuthToken, f.AuthToken)
+	setBool(&config.SendRequestID, f.SendRequestID)
+	setString(&config.LCDURL, f.LCDURL)
+	setBool(&config.LCDFallback, f.LCDFallback)
+	setDuration(&config.KeepaliveTime, f.KeepaliveTime)
+	setDuration(&config.KeepaliveTimeout, f.KeepaliveTimeout)
+	setDuration(&config.IdleTimeout, f.IdleTimeout)
+	setInt(&config.MaxRecvMsgSize, f.MaxRecvMsgSize)
+	setInt(&config.MaxSendMsgSize, f.MaxSendMsgSize)
+	setBool(&config.UseCompression, f.UseCompression)
+	setBool(&config.VerifyRoot, f.VerifyRoot)
+	setBool(&config.StrictLeaves, f.StrictLeaves)
+	setBool(&config.DedupLeaves, f.DedupLeaves)
+	setInt(&config.MaxLeaves, f.MaxLeaves)
+	setInt(&config.MaxBlockLag, f.MaxBlockLag)
+	if f.MerkleHash != nil {
+		hasher, err := HasherByName(*f.MerkleHash)
+		if err != nil {
+			return err
+		}
+		config.Merkle.Hasher = hasher
+	}
+	setBool(&config.Merkle.SortPairs, f.MerkleSortPairs)
+	setBool(&config.Merkle.OpenZeppelin, f.MerkleOpenZeppelin)
+	setBool(&config.CacheEnabled, f.CacheEnabled)
+	setDuration(&config.CacheTTL, f.CacheTTL)
+	setDuration(&config.CacheNegativeTTL, f.CacheNegativeTTL)
+	setBool(&config.WarmCacheOnStart, f.WarmCacheOnStart)
+	setBool(&config.CacheStaleWhileRevalidate, f.CacheStale)
+	setInt(&config.CacheMaxEntries, f.CacheMaxEntries)
+	setFloat(&config.RateLimitQPS, f.RateLimitQPS)
+	setInt(&config.RateLimitBurst, f.RateLimitBurst)
+	setInt(&config.BatchConcurrency, f.BatchConcurrency)
+	setBool(&config.EnableExpvar, f.EnableExpvar)
+	setBool(&config.EnableTracing, f.EnableTracing)
+	setDuration(&config.HealthCheckInterval, f.HealthCheckInterval)
+	setDuration(&config.UnhealthyThreshold, f.UnhealthyThreshold)
+	return nil
+}
+
+func setString(dst *string, src *string) {
+	if src != nil {
+		*dst = *src
+	}
+}
+
+func setInt(dst *int, src *int) {
+	if src != nil {
+		*dst = *src
+	}
+}
+
+func setFloat(dst *float64, src *float64) {
+	if src != nil {
+		*dst = *src
+	}
+}
+
+func setBool(dst *bool, src *bool) {
+	if src != nil {
+		*dst = *src
+	}
+}
+
+func setDuration(dst *time.Duration, src *configDuration) {
+	if src != nil {
+		*dst = time.Duration(*src)
+	}
+}
@@ -0,0 +1,86 @@
+package clients
+
+import (
+	"flag"
+	"strings"
+)
+
+// listFlag is a comma-separated list flag, such as --grpc-urls a:9090,b:9090
+type listFlag struct {
+	list *[]string
+}
+
+func (f listFlag) String() string {
+	if f.list == nil {
+		return ""
+	}
+	return strings.Join(*f.list, ",")
+}
+
+func (f listFlag) Set(value string) error {
+	*f.list = splitList(value)
+	return nil
+}
+
+// RegisterFlags binds command-line flags such as --grpc-url, --contract-addr,
+// --max-retries and --initial-backoff to the fields of c, using their current
+// values as the flag defaults. Each flag is named after the environment variable
+// for the same field, lowercased with dashes. Pass fs to ResolveClientConfig after
+// parsing to layer the flags that were set over the environment and a config file.
+func RegisterFlags(fs *flag.FlagSet, c *ClientConfig) {
+	fs.StringVar(&c.GrpcURL, "grpc-url", c.GrpcURL, "gRPC endpoint of the node")
+	fs.Var(listFlag{&c.GrpcURLs}, "grpc-urls", "Comma-separated failover endpoints, tried in order")
+	fs.StringVar(&c.ContractAddr, "contract-addr", c.ContractAddr, "Address of the merkle tree contract")
+	fs.IntVar(&c.MaxRetries, "max-retries", c.MaxRetries, "Connection attempts, -1 retries forever")
+	fs.DurationVar(&c.InitialBackoff, "initial-backoff", c.InitialBackoff, "Wait before the first reconnect")
+	fs.DurationVar(&c.MaxBackoff, "max-backoff", c.MaxBackoff, "Longest wait between reconnects")
+	fs.BoolVar(&c.BackoffJitter, "backoff-jitter", c.BackoffJitter, "Randomize backoffs")
+	fs.DurationVar(&c.MaxElapsedTime, "max-elapsed-time", c.MaxElapsedTime, "Stop retrying after this long, 0 retries forever")
+	fs.DurationVar(&c.ConnectionTimeout, "connection-timeout", c.ConnectionTimeout, "Timeout for connecting and verifying the node")
+	fs.BoolVar(&c.LazyConnect, "lazy-connect", c.LazyConnect, "Connect on the first query instead of at startup")
+	fs.BoolVar(&c.NonBlockingDial, "grpc-non-blocking-dial", c.NonBlockingDial, "Start without waiting for or verifying the node")
+	fs.BoolVar(&c.SkipVerification, "skip-verification", c.SkipVerification, "Connect without checking the contract exists")
+	fs.DurationVar(&c.QueryTimeout, "query-timeout", c.QueryTimeout, "Timeout of a single query, 0 disables it")
+	fs.IntVar(&c.QueryMaxRetries, "query-max-retries", c.QueryMaxRetries, "Retries of a query that fails transiently")
+	fs.DurationVar(&c.QueryInitialBackoff, "query-initial-backoff", c.QueryInitialBackoff, "Wait before the first query retry")
+	fs.DurationVar(&c.QueryMaxBackoff, "query-max-backoff", c.QueryMaxBackoff, "Longest wait between query retries")
+	fs.BoolVar(&c.UseTLS, "grpc-tls", c.UseTLS, "Connect over TLS")
+	fs.StringVar(&c.TLSServerName, "grpc-tls-server-name", c.TLSServerName, "Server name the certificate is verified against")
+	fs.StringVar(&c.TLSCAPath, "grpc-tls-ca", c.TLSCAPath, "PEM bundle of additional trusted CAs")
+	fs.StringVar(&c.TLSClientCertPath, "grpc-tls-cert", c.TLSClientCertPath, "Client certificate for mutual TLS")
+	fs.StringVar(&c.TLSClientKeyPath, "grpc-tls-key", c.TLSClientKeyPath, "Private key of the client certificate")
+	fs.StringVar(&c.LCDURL, "lcd-url", c.LCDURL, "REST endpoint, e.g. http://host:1317")
+	fs.BoolVar(&c.LCDFallback, "lcd-fallback", c.LCDFallback, "Retry queries over --lcd-url when gRPC is unavailable")
+	fs.BoolVar(&c.CacheEnabled, "cache-enabled", c.CacheEnabled, "Cache fetched trees in memory")
+	fs.DurationVar(&c.CacheTTL, "cache-ttl", c.CacheTTL, "How long a cached tree is served")
+	fs.Float64Var(&c.RateLimitQPS, "rate-limit-qps", c.RateLimitQPS, "Queries per second, 0 is unlimited")
+	fs.IntVar(&c.BatchConcurrency, "batch-concurrency", c.BatchConcurrency, "Trees fetched at once by batch queries")
+}
+
+// ResolveClientConfig builds the client configuration from, in increasing order of
+// precedence, DefaultClientConfig, the config file at path (skipped when path is
+// empty), the environment, and the flags of fs that were set on the command line.
+// fs must have been parsed and have the flags of RegisterFlags; flags left at their
+// default do not override anything. Like LoadClientConfigFromFile it does not
+// validate the result; NewCosmosQueryClient does.
+func ResolveClientConfig(fs *flag.FlagSet, path string) (ClientConfig, error) {
+	config := DefaultClientConfig()
+	if path != "" {
+		if err := loadConfigFile(path, &config); err != nil {
+			return config, err
+		}
+	}
+	config.loadEnv()
+
+	// Replay the flags that were set onto a flag set bound to config
+	bound := flag.NewFlagSet(fs.Name(), flag.ContinueOnError)
+	RegisterFlags(bound, &config)
+	var err error
+	fs.Visit(func(f *flag.Flag) {
+		if err != nil || bound.Lookup(f.Name) == nil {
+			return
+		}
+		err = bound.Set(f.Name, f.Value.String())
+	})
+	return config, err
+}
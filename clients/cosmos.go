@@ -2,55 +2,333 @@ package clients
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
 	"fmt"
-	"log"
-	"math"
+	"log/slog"
+	"math/rand"
+	"os"
+	"strings"
+	"sync"
 	"time"
 
 	wasmtypes "github.com/CosmWasm/wasmd/x/wasm/types"
-	"github.com/Layer-Edge/light-node/utils"
+	"github.com/go-resty/resty/v2"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/sync/singleflight"
+	"golang.org/x/time/rate"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/encoding/gzip"
+	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/metadata"
 )
 
 // ClientConfig holds all configurable parameters for the clients package
 type ClientConfig struct {
 	GrpcURL        string
+	GrpcURLs       []string // Failover endpoints tried in order, takes precedence over GrpcURL
 	ContractAddr   string
+	// gRPC service config JSON applied to every connection. Empty uses
+	// roundRobinServiceConfig for dns:/// endpoints, spreading calls over every
+	// address the name resolves to, and gRPC's defaults otherwise.
+	ServiceConfig string
 	// Retry configuration
 	MaxRetries     int
 	InitialBackoff time.Duration
 	MaxBackoff     time.Duration
+	BackoffJitter  bool          // Sleep a random duration in [0, backoff) to spread out reconnecting nodes
+	MaxElapsedTime time.Duration // Give up connecting once this much time has passed since the first attempt, 0 means no limit
+	// Strategy for the wait between connection retries, nil means
+	// ExponentialBackoff over InitialBackoff and MaxBackoff
+	Backoff Backoff
+	// Backoff carries over between reconnects so a flapping connection keeps
+	// backing off; the health check resets it once the connection has been Ready
+	// this long. 0 means twice MaxBackoff.
+	BackoffResetAfter time.Duration
+	// Retries of individual queries on transient gRPC errors, 0 disables them.
+	// Kept separate from MaxRetries, whose default of -1 would make a failing
+	// query hang forever.
+	QueryMaxRetries int
+	// Wait before the first query retry, doubled for each retry after it up to
+	// QueryMaxBackoff. Kept short and apart from the reconnect backoff, since a
+	// caller is waiting on the query; 0 retries straight away.
+	QueryInitialBackoff time.Duration
+	QueryMaxBackoff     time.Duration
+	QueryBackoffJitter  bool // Sleep a random duration in [0, backoff) before each query retry
+	// Strategy for the wait between query retries, nil means ExponentialBackoff
+	// over QueryInitialBackoff and QueryMaxBackoff
+	QueryBackoff Backoff
+	// Circuit breaker, opens after FailureThreshold consecutive transport failures
+	// and fails queries fast for OpenDuration. A threshold of 0 disables it.
+	FailureThreshold int
+	OpenDuration     time.Duration
 	// Connection timeout
 	ConnectionTimeout time.Duration
+	// Return from Init and NewCosmosQueryClient without connecting; the first
+	// query connects instead, so startup does not depend on the node being up
+	LazyConnect bool
+	// Use each new connection as soon as it is created, letting gRPC connect in
+	// the background, instead of waiting for it to become ready and pass
+	// verification. Init then never fails on an unreachable node, but a wrong
+	// endpoint or contract only shows up as failing queries, and failover to the
+	// next endpoint waits for the health check or a query that finds the
+	// connection dead.
+	NonBlockingDial bool
+	// How a new connection is checked before it is used, see VerifyStrategy.
+	// VerifyQuery is the JSON smart query sent by VerifySmartQuery.
+	VerifyStrategy VerifyStrategy
+	VerifyQuery    json.RawMessage
+	// Accept a connection as soon as it is Ready, without running VerifyStrategy,
+	// e.g. while bootstrapping before the contract is deployed
+	SkipVerification bool
+	// Per-attempt query timeout, applied only when the caller's context has no
+	// deadline of its own. 0 disables it.
+	QueryTimeout time.Duration
+	// Let queries wait, within their deadline, for a connection in transient
+	// failure to recover instead of failing fast
+	WaitForReady bool
+	// TLS configuration, plaintext is used unless UseTLS is set
+	UseTLS            bool
+	TLSServerName     string // Overrides the server name used to verify the certificate
+	TLSCAPath         string // PEM bundle of additional CAs trusted for the server certificate
+	TLSClientCertPath string // Client certificate presented for mutual TLS
+	TLSClientKeyPath  string // Private key matching TLSClientCertPath
+	// Send an x-request-id header with every query, taken from
+	// ContextWithRequestID or generated, to correlate queries with the node's logs
+	SendRequestID bool
+	// REST (LCD) endpoint such as http://host:1317 that serves queries when gRPC
+	// fails with a transport error, if LCDFallback is set
+	LCDURL      string
+	LCDFallback bool
+	// Bearer token sent as "authorization: Bearer <token>" on every call, empty
+	// sends none. Without UseTLS it travels in plaintext.
+	AuthToken string
+	// Computes extra headers for each call from the caller's context, such as a
+	// tenant ID or a short-lived signed token. It runs before every attempt of
+	// every call, verification included, and before LCD requests, and its values
+	// are added to any metadata the context already carries. nil adds none.
+	MetadataFunc func(ctx context.Context) metadata.MD
+	// Keepalive pings keep idle connections alive behind NAT/load balancers
+	KeepaliveTime    time.Duration // Interval between pings, 0 disables keepalive
+	KeepaliveTimeout time.Duration // Time to wait for a ping ack before closing the connection
+	// Let the connection go Idle, closing its transport, after this long without a
+	// query. The next query reconnects and waits for the connection rather than
+	// failing. Keepalive pings do not count as activity. 0 keeps gRPC's default of
+	// 30 minutes.
+	IdleTimeout time.Duration
+	// Message size limits in bytes, 0 keeps the gRPC default of 4MB for received
+	// messages. Trees with many leaves can exceed it, which shows up as a
+	// ResourceExhausted "received message larger than max" error.
+	MaxRecvMsgSize int
+	MaxSendMsgSize int
+	// Gzip-compress requests and advertise gzip so the node can compress the
+	// large JSON responses of trees with many leaves
+	UseCompression bool
+	// Extra options appended after the built-in ones when dialing, so they take
+	// precedence wherever gRPC lets a later option override an earlier one
+	DialOptions []grpc.DialOption
+	// Unary interceptors run, in order, around every call on the connection,
+	// see LoggingInterceptor and LatencyInterceptor
+	Interceptors []grpc.UnaryClientInterceptor
+	// Client-side limit on contract queries per second, each retry included, with
+	// bursts of up to RateLimitBurst. QPS 0 disables the limit; burst 0 uses the
+	// QPS rounded up.
+	RateLimitQPS   float64
+	RateLimitBurst int
+	// Maximum number of queries in flight for batch operations such as
+	// GetMerkleTreeDataBatch and QueryAllTrees, 0 or 1 makes them serial. Each query is
+	// a gRPC stream, so values above the node's max concurrent streams just queue.
+	BatchConcurrency int
+	// Publish the client's QueryStats on /debug/vars through expvar
+	EnableExpvar bool
+	// Trace every gRPC call and tree query with OpenTelemetry, using
+	// TracerProvider or, when it is nil, the global provider
+	EnableTracing  bool
+	TracerProvider trace.TracerProvider
+	// Recompute each fetched tree's root from its leaves and reject mismatches
+	VerifyRoot bool
+	// Reject fetched trees whose leaves are not hex of one consistent length, see
+	// MerkleTree.ValidateLeaves. Only for contracts that store hex digests as leaves.
+	StrictLeaves bool
+	// Drop repeated leaves from fetched trees, see MerkleTree.DedupLeaves. Root is
+	// checked before deduplication, so the returned leaves may no longer hash to it.
+	DedupLeaves bool
+	// Most leaves a fetched tree, or tree IDs a listing, may hold before the
+	// response is rejected as invalid, bounding the memory a hostile contract can
+	// make the client allocate. 0 means no limit.
+	MaxLeaves int
+	// Reject tree fetches answered at a block height more than MaxBlockLag blocks
+	// behind ReferenceHeight, such as another node's LatestBlockHeight, so a
+	// lagging node's outdated trees are not acted on. Off unless both are set.
+	// Responses without a height, or whose reference cannot be fetched, are
+	// accepted with a warning.
+	MaxBlockLag     int
+	ReferenceHeight func(ctx context.Context) (int64, error)
+	// Hashing used for merkle roots and proofs, must match the contract
+	Merkle MerkleConfig
+	// Optional LRU cache of tree data in front of GetMerkleTreeData
+	CacheEnabled    bool
+	CacheTTL        time.Duration // How long a cached tree is served, 0 keeps it until evicted
+	CacheMaxEntries int           // Maximum number of cached trees, 0 means unbounded
+	// Keep serving a tree past CacheTTL while it is refreshed in the background, so
+	// a brief outage only fails lookups of trees that were never cached. See
+	// GetMerkleTreeDataCached for telling stale trees apart.
+	CacheStaleWhileRevalidate bool
+	// Fetch every tree into the cache once connected, see WarmCache. Ignored with
+	// LazyConnect, which connects on the first query instead.
+	WarmCacheOnStart bool
+	// How long a tree ID the contract reported missing keeps answering
+	// ErrTreeNotFound without a query, 0 disables negative caching
+	CacheNegativeTTL time.Duration
+	// Background health check, see StartHealthCheck
+	HealthCheckInterval time.Duration // How often the connection state is inspected, 0 means every 15 seconds
+	UnhealthyThreshold  time.Duration // How long the connection may stay unhealthy before reconnecting
+	// Logger for this client's events, nil uses the package logger set by SetLogger
+	Logger *slog.Logger
 }
 
-// Global configuration with default values
-var globalClientConfig = ClientConfig{
-	GrpcURL:           "34.57.133.111:9090",                                                 // Default gRPC endpoint
-	ContractAddr:      "cosmos1ufs3tlq4umljk0qfe8k5ya0x6hpavn897u2cnf9k0en9jr7qarqqt56709", // Default contract address
-	MaxRetries:        -1,                   // -1 means retry indefinitely
-	InitialBackoff:    30 * time.Second,      // Start with 30 second backoff
-	MaxBackoff:        10 * time.Minute,     // Maximum backoff of 10 minutes
-	ConnectionTimeout: 10 * time.Second,     // Connection verification timeout
+// DefaultClientConfig returns the built-in default configuration
+func DefaultClientConfig() ClientConfig {
+	return ClientConfig{
+		GrpcURL:             "34.57.133.111:9090",                                                // Default gRPC endpoint
+		ContractAddr:        "cosmos1ufs3tlq4umljk0qfe8k5ya0x6hpavn897u2cnf9k0en9jr7qarqqt56709", // Default contract address
+		MaxRetries:          -1,                                                                  // -1 means retry indefinitely
+		InitialBackoff:      30 * time.Second,                                                    // Start with 30 second backoff
+		MaxBackoff:          10 * time.Minute,                                                    // Maximum backoff of 10 minutes
+		BackoffJitter:       false,                                                               // Deterministic backoff unless jitter is configured
+		QueryMaxRetries:     0,                                                                   // Fail a query on its first error unless retries are configured
+		QueryInitialBackoff: 100 * time.Millisecond,                                              // First query retry after 100 milliseconds
+		QueryMaxBackoff:     2 * time.Second,                                                     // Query retries wait at most 2 seconds
+		QueryBackoffJitter:  true,                                                                // Spread out retries of queries that failed together
+		FailureThreshold:    0,                                                                   // No circuit breaker unless configured
+		OpenDuration:        30 * time.Second,                                                    // Fail fast for 30 seconds before probing again
+		ConnectionTimeout:   10 * time.Second,                                                    // Connection verification timeout
+		QueryTimeout:        15 * time.Second,                                                    // Give up on a single query after 15 seconds
+		KeepaliveTime:       30 * time.Second,                                                    // Ping the server every 30 seconds
+		KeepaliveTimeout:    10 * time.Second,                                                    // Drop the connection if a ping is not acked within 10 seconds
+		MaxLeaves:           1 << 22,                                                             // Reject responses with more than about 4 million leaves
+		Merkle:              DefaultMerkleConfig(),                                               // SHA-256, as used by the production contract
+		CacheTTL:            10 * time.Minute,                                                    // Serve cached trees for up to 10 minutes
+		CacheMaxEntries:     256,                                                                 // Keep at most 256 trees in memory
+		CacheNegativeTTL:    30 * time.Second,                                                    // Remember missing tree IDs for 30 seconds
+		BatchConcurrency:    8,                                                                   // Fetch up to 8 trees at once
+		HealthCheckInterval: 15 * time.Second,                                                    // Inspect the connection state every 15 seconds
+		UnhealthyThreshold:  time.Minute,                                                         // Reconnect after a minute without a usable connection
+	}
 }
 
+// Global configuration with default values
+var globalClientConfig = DefaultClientConfig()
+
 // InitClientConfig initializes the client configuration with environment variables or defaults
 func InitClientConfig() {
-	globalClientConfig.GrpcURL = utils.GetEnv("GRPC_URL", "0.0.0.0:9090")
-	globalClientConfig.ContractAddr = utils.GetEnv("CONTRACT_ADDR", "cosmos1ufs3tlq4umljk0qfe8k5ya0x6hpavn897u2cnf9k0en9jr7qarqqt56709")
+	// Without GRPC_URL the node talks to a gRPC server on this host
+	globalClientConfig.GrpcURL = getEnv("GRPC_URL", "0.0.0.0:9090")
+	globalClientConfig.loadEnv()
 
-	log.Printf("Initialized client configuration: GRPC_URL=%s, CONTRACT_ADDR=%s",
-		globalClientConfig.GrpcURL, globalClientConfig.ContractAddr)
+	logger.Info("Initialized client configuration", "config", globalClientConfig.String())
+}
+
+// ClientConfigFromEnv returns the configuration InitClientConfig would build from
+// the environment and DefaultClientConfig, without touching the global
+// configuration. It reads every variable ToEnv writes.
+func ClientConfigFromEnv() ClientConfig {
+	c := DefaultClientConfig()
+	c.GrpcURL = getEnv("GRPC_URL", "0.0.0.0:9090")
+	c.loadEnv()
+	return c
+}
+
+// loadEnv overrides c with the configuration variables set in the environment.
+// Variables that are unset, or fail to parse, leave the field as it is.
+func (c *ClientConfig) loadEnv() {
+	c.GrpcURL = getEnv("GRPC_URL", c.GrpcURL)
+	c.GrpcURLs = getEnvList("GRPC_URLS", c.GrpcURLs)
+	c.ContractAddr = getEnv("CONTRACT_ADDR", c.ContractAddr)
+	c.ServiceConfig = getEnv("GRPC_SERVICE_CONFIG", c.ServiceConfig)
+	c.MaxRetries = getEnvInt("MAX_RETRIES", c.MaxRetries)
+	c.InitialBackoff = getEnvDuration("INITIAL_BACKOFF", c.InitialBackoff)
+	c.MaxBackoff = getEnvDuration("MAX_BACKOFF", c.MaxBackoff)
+	c.BackoffJitter = getEnvBool("BACKOFF_JITTER", c.BackoffJitter)
+	c.MaxElapsedTime = getEnvDuration("MAX_ELAPSED_TIME", c.MaxElapsedTime)
+	c.BackoffResetAfter = getEnvDuration("BACKOFF_RESET_AFTER", c.BackoffResetAfter)
+	c.QueryMaxRetries = getEnvInt("QUERY_MAX_RETRIES", c.QueryMaxRetries)
+	c.QueryInitialBackoff = getEnvDuration("QUERY_INITIAL_BACKOFF", c.QueryInitialBackoff)
+	c.QueryMaxBackoff = getEnvDuration("QUERY_MAX_BACKOFF", c.QueryMaxBackoff)
+	c.QueryBackoffJitter = getEnvBool("QUERY_BACKOFF_JITTER", c.QueryBackoffJitter)
+	c.FailureThreshold = getEnvInt("FAILURE_THRESHOLD", c.FailureThreshold)
+	c.OpenDuration = getEnvDuration("OPEN_DURATION", c.OpenDuration)
+	c.ConnectionTimeout = getEnvDuration("CONNECTION_TIMEOUT", c.ConnectionTimeout)
+	c.LazyConnect = getEnvBool("LAZY_CONNECT", c.LazyConnect)
+	c.NonBlockingDial = getEnvBool("GRPC_NON_BLOCKING_DIAL", c.NonBlockingDial)
+	if name := getEnv("VERIFY_STRATEGY", ""); name != "" {
+		if strategy, err := ParseVerifyStrategy(name); err == nil {
+			c.VerifyStrategy = strategy
+		} else {
+			logger.Warn("Ignoring unparseable environment variable", "key", "VERIFY_STRATEGY", "value", name, "error", err)
+		}
+	}
+	if query := getEnv("VERIFY_QUERY", ""); query != "" {
+		c.VerifyQuery = json.RawMessage(query)
+	}
+	c.SkipVerification = getEnvBool("SKIP_VERIFICATION", c.SkipVerification)
+	c.UseTLS = getEnvBool("GRPC_TLS", c.UseTLS)
+	c.TLSServerName = getEnv("GRPC_TLS_SERVER_NAME", c.TLSServerName)
+	c.TLSCAPath = getEnv("GRPC_TLS_CA", c.TLSCAPath)
+	c.TLSClientCertPath = getEnv("GRPC_TLS_CERT", c.TLSClientCertPath)
+	c.TLSClientKeyPath = getEnv("GRPC_TLS_KEY", c.TLSClientKeyPath)
+	c.AuthToken = getEnv("GRPC_AUTH_TOKEN", c.AuthToken)
+	c.LCDURL = getEnv("LCD_URL", c.LCDURL)
+	c.LCDFallback = getEnvBool("LCD_FALLBACK", c.LCDFallback)
+	c.SendRequestID = getEnvBool("GRPC_REQUEST_ID", c.SendRequestID)
+	c.QueryTimeout = getEnvDuration("QUERY_TIMEOUT", c.QueryTimeout)
+	c.WaitForReady = getEnvBool("GRPC_WAIT_FOR_READY", c.WaitForReady)
+	c.KeepaliveTime = getEnvDuration("GRPC_KEEPALIVE_TIME", c.KeepaliveTime)
+	c.KeepaliveTimeout = getEnvDuration("GRPC_KEEPALIVE_TIMEOUT", c.KeepaliveTimeout)
+	c.IdleTimeout = getEnvDuration("GRPC_IDLE_TIMEOUT", c.IdleTimeout)
+	c.MaxRecvMsgSize = getEnvInt("GRPC_MAX_RECV_MSG_SIZE", c.MaxRecvMsgSize)
+	c.MaxSendMsgSize = getEnvInt("GRPC_MAX_SEND_MSG_SIZE", c.MaxSendMsgSize)
+	c.UseCompression = getEnvBool("GRPC_COMPRESSION", c.UseCompression)
+	c.HealthCheckInterval = getEnvDuration("HEALTH_CHECK_INTERVAL", c.HealthCheckInterval)
+	c.UnhealthyThreshold = getEnvDuration("UNHEALTHY_THRESHOLD", c.UnhealthyThreshold)
+	c.EnableExpvar = getEnvBool("ENABLE_EXPVAR", c.EnableExpvar)
+	c.EnableTracing = getEnvBool("ENABLE_TRACING", c.EnableTracing)
+	c.VerifyRoot = getEnvBool("VERIFY_ROOT", c.VerifyRoot)
+	c.StrictLeaves = getEnvBool("STRICT_LEAVES", c.StrictLeaves)
+	c.DedupLeaves = getEnvBool("DEDUP_LEAVES", c.DedupLeaves)
+	c.MaxLeaves = getEnvInt("MAX_LEAVES", c.MaxLeaves)
+	c.MaxBlockLag = getEnvInt("MAX_BLOCK_LAG", c.MaxBlockLag)
+	if name := getEnv("MERKLE_HASH", ""); name != "" {
+		if hasher, err := HasherByName(name); err == nil {
+			c.Merkle.Hasher = hasher
+		} else {
+			logger.Warn("Ignoring unparseable environment variable", "key", "MERKLE_HASH", "value", name, "error", err)
+		}
+	}
+	c.Merkle.LeafPrefix = getEnvHex("MERKLE_LEAF_PREFIX", c.Merkle.LeafPrefix)
+	c.Merkle.NodePrefix = getEnvHex("MERKLE_NODE_PREFIX", c.Merkle.NodePrefix)
+	c.Merkle.SortPairs = getEnvBool("MERKLE_SORT_PAIRS", c.Merkle.SortPairs)
+	c.Merkle.OpenZeppelin = getEnvBool("MERKLE_OPENZEPPELIN", c.Merkle.OpenZeppelin)
+	c.RateLimitQPS = getEnvFloat("RATE_LIMIT_QPS", c.RateLimitQPS)
+	c.RateLimitBurst = getEnvInt("RATE_LIMIT_BURST", c.RateLimitBurst)
+	c.BatchConcurrency = getEnvInt("BATCH_CONCURRENCY", c.BatchConcurrency)
+	c.CacheEnabled = getEnvBool("CACHE_ENABLED", c.CacheEnabled)
+	c.CacheStaleWhileRevalidate = getEnvBool("CACHE_STALE_WHILE_REVALIDATE", c.CacheStaleWhileRevalidate)
+	c.WarmCacheOnStart = getEnvBool("CACHE_WARM_ON_START", c.WarmCacheOnStart)
+	c.CacheTTL = getEnvDuration("CACHE_TTL", c.CacheTTL)
+	c.CacheMaxEntries = getEnvInt("CACHE_MAX_ENTRIES", c.CacheMaxEntries)
+	c.CacheNegativeTTL = getEnvDuration("CACHE_NEGATIVE_TTL", c.CacheNegativeTTL)
 }
 
 // SetClientConfig allows overriding the configuration programmatically
 func SetClientConfig(config ClientConfig) {
 	globalClientConfig = config
-	log.Printf("Updated client configuration: GRPC_URL=%s, CONTRACT_ADDR=%s",
-		globalClientConfig.GrpcURL, globalClientConfig.ContractAddr)
+	logger.Info("Updated client configuration", "config", globalClientConfig.String())
 }
 
 // GetClientConfig returns a copy of the current configuration
@@ -58,10 +336,22 @@ func GetClientConfig() ClientConfig {
 	return globalClientConfig
 }
 
+// Endpoints returns the gRPC endpoints to try in order, falling back to GrpcURL
+// when no failover list is configured
+func (c ClientConfig) Endpoints() []string {
+	if len(c.GrpcURLs) > 0 {
+		return c.GrpcURLs
+	}
+	return []string{c.GrpcURL}
+}
+
 type MerkleTree struct {
 	Root     string   `json:"root"`
 	Leaves   []string `json:"leaves"`
 	Metadata string   `json:"metadata"`
+	// Block height the node reported answering at, 0 when unknown. It is not part
+	// of the contract's response.
+	Height int64 `json:"-"`
 }
 
 type QueryGetTree struct {
@@ -75,28 +365,84 @@ type QueryListTreeIDs struct {
 	} `json:"list_merkle_tree_ids"`
 }
 
+// QueryClient is the read API of the merkle tree contract. CosmosQueryClient
+// implements it against a live gRPC node; consumers that accept the interface can
+// be tested with a fake instead.
+type QueryClient interface {
+	GetMerkleTreeData(id string) (*MerkleTree, error)
+	GetMerkleTreeDataContext(ctx context.Context, id string) (*MerkleTree, error)
+	ListMerkleTreeIds() ([]string, error)
+	ListMerkleTreeIdsContext(ctx context.Context) ([]string, error)
+	Close()
+}
+
+var _ QueryClient = (*CosmosQueryClient)(nil)
+
 type CosmosQueryClient struct {
-	conn        *grpc.ClientConn
-	queryClient wasmtypes.QueryClient
-	config      ClientConfig
+	mu             sync.RWMutex // Guards the connection, closed, endpointIdx, backoffLevel, the last query outcome, lazy and stopBackground
+	conn           *grpc.ClientConn
+	activeEndpoint string // Endpoint conn is connected to
+	queryClient    wasmtypes.QueryClient
+	reconnecting   bool
+	closed         bool
+	inflight       sync.WaitGroup       // Queries in progress, drained by CloseContext
+	background     sync.WaitGroup       // Health check loops and lazy connection attempts, stopped by CloseContext
+	lastSuccess    time.Time            // When a query last got an answer from the node
+	lastErr        error                // Most recent query failure
+	lastErrAt      time.Time
+	lazy           *lazyConnect         // Connection attempt in progress in LazyConnect mode
+	stopBackground []context.CancelFunc // Cancels the health check loops
+	config         ClientConfig
+	endpointIdx    int // Index into config.Endpoints() of the last successful endpoint
+	backoffLevel   int // Retries already backed off since the connection was last healthy
+	randMu         sync.Mutex
+	rand           *rand.Rand // Jitter source, guarded by randMu
+	stats          clientStats
+	breaker        circuitBreaker
+	cacheOnce      sync.Once
+	cache          *treeCache
+	flight         singleflight.Group // Shares concurrent fetches of the same tree ID
+	reconnFlight   singleflight.Group // Shares reconnects of the health check and failed queries
+	expvarOnce     sync.Once
+	lcdOnce        sync.Once
+	limiterOnce    sync.Once
+	limiter        *rate.Limiter // Created on first use when RateLimitQPS is set
+	infoMu         sync.Mutex
+	info           *wasmtypes.ContractInfo // Cached by ContractInfo
+	infoConn       *grpc.ClientConn        // Connection info was fetched over
+	infoAddr       string                  // Contract address info was fetched for
+	lcdClient      *resty.Client // LCD fallback client, created on first use
 }
 
 func (cqc *CosmosQueryClient) Init() error {
+	return cqc.InitContext(context.Background())
+}
+
+// InitContext initializes the client with the global configuration. Cancelling ctx
+// aborts the connection retry loop, which is otherwise unbounded when MaxRetries is -1
+func (cqc *CosmosQueryClient) InitContext(ctx context.Context) error {
 	// Use the global configuration
+	if err := globalClientConfig.Validate(); err != nil {
+		return err
+	}
 	cqc.config = globalClientConfig
-	return cqc.connect()
+	return cqc.start(ctx)
 }
 
 // InitWithConfig initializes the client with a specific configuration
 func (cqc *CosmosQueryClient) InitWithConfig(config ClientConfig) error {
+	if err := config.Validate(); err != nil {
+		return err
+	}
 	cqc.config = config
-	return cqc.connect()
+	return cqc.start(context.Background())
 }
 
-// verifyConnection checks if the connection is actually usable by making a test query
-func (cqc *CosmosQueryClient) verifyConnection(conn *grpc.ClientConn) error {
+// verifyConnection checks if the connection is actually usable by making a test
+// query against the contract at addr
+func (cqc *CosmosQueryClient) verifyConnection(ctx context.Context, conn *grpc.ClientConn, addr string) error {
 	// Create a deadline for connection verification
-	ctx, cancel := context.WithTimeout(context.Background(), cqc.config.ConnectionTimeout)
+	ctx, cancel := context.WithTimeout(ctx, cqc.config.ConnectionTimeout)
 	defer cancel()
 
 	// Wait for connection to become ready with a timeout
@@ -109,131 +455,600 @@ func (cqc *CosmosQueryClient) verifyConnection(conn *grpc.ClientConn) error {
 	
 	// Try to make a simple query to verify the connection works
 	queryClient := wasmtypes.NewQueryClient(conn)
-	_, err := queryClient.ContractInfo(
-		ctx,
-		&wasmtypes.QueryContractInfoRequest{
-			Address: cqc.config.ContractAddr,
-		},
-	)
+	var err error
+	switch cqc.config.VerifyStrategy {
+	case VerifyConnectivity:
+		// Reaching Ready is all this strategy asks for
+	case VerifySmartQuery:
+		_, err = queryClient.SmartContractState(
+			ctx,
+			&wasmtypes.QuerySmartContractStateRequest{
+				Address:   addr,
+				QueryData: wasmtypes.RawContractMessage(cqc.config.VerifyQuery),
+			},
+		)
+	default:
+		var res *wasmtypes.QueryContractInfoResponse
+		res, err = queryClient.ContractInfo(
+			ctx,
+			&wasmtypes.QueryContractInfoRequest{
+				Address: addr,
+			},
+		)
+		if err == nil {
+			cqc.storeContractInfo(conn, addr, res.ContractInfo)
+		}
+	}
 	
 	if err != nil {
-		return fmt.Errorf("connection verification failed: %v", err)
+		return fmt.Errorf("connection verification failed: %w", err)
 	}
 	
 	return nil
 }
 
-// connect attempts to establish a connection with exponential backoff retry
-func (cqc *CosmosQueryClient) connect() error {
-	backoff := cqc.config.InitialBackoff
+// transportCredentials builds the gRPC transport credentials for the configuration,
+// using the system cert pool plus any configured CA bundle when TLS is enabled
+func (cqc *CosmosQueryClient) transportCredentials() (credentials.TransportCredentials, error) {
+	if !cqc.config.UseTLS {
+		return insecure.NewCredentials(), nil
+	}
+
+	certPath, keyPath := cqc.config.TLSClientCertPath, cqc.config.TLSClientKeyPath
+	if (certPath == "") != (keyPath == "") {
+		return nil, fmt.Errorf("invalid TLS configuration: client certificate and key must be set together (cert=%q, key=%q)",
+			certPath, keyPath)
+	}
+
+	rootCAs, err := x509.SystemCertPool()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load system cert pool: %v", err)
+	}
+
+	if cqc.config.TLSCAPath != "" {
+		pem, err := os.ReadFile(cqc.config.TLSCAPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA bundle %s: %v", cqc.config.TLSCAPath, err)
+		}
+		if !rootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA bundle %s", cqc.config.TLSCAPath)
+		}
+	}
+
+	tlsConfig := &tls.Config{
+		RootCAs:    rootCAs,
+		ServerName: cqc.config.TLSServerName,
+		MinVersion: tls.VersionTLS12,
+	}
+
+	if certPath != "" {
+		cert, err := tls.LoadX509KeyPair(certPath, keyPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %v", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return credentials.NewTLS(tlsConfig), nil
+}
+
+// roundRobinServiceConfig balances calls across all resolved addresses
+const roundRobinServiceConfig = `{"loadBalancingConfig": [{"round_robin": {}}]}`
+
+// dialOptions returns the options used to create a gRPC connection to endpoint
+func (cqc *CosmosQueryClient) dialOptions(endpoint string, creds credentials.TransportCredentials) []grpc.DialOption {
+	opts := []grpc.DialOption{
+		grpc.WithTransportCredentials(creds),
+	}
+
+	serviceConfig := cqc.config.ServiceConfig
+	if serviceConfig == "" && strings.HasPrefix(endpoint, "dns:") {
+		serviceConfig = roundRobinServiceConfig
+	}
+	if serviceConfig != "" {
+		opts = append(opts, grpc.WithDefaultServiceConfig(serviceConfig))
+	}
+
+	if cqc.config.KeepaliveTime > 0 {
+		opts = append(opts, grpc.WithKeepaliveParams(keepalive.ClientParameters{
+			Time:                cqc.config.KeepaliveTime,
+			Timeout:             cqc.config.KeepaliveTimeout,
+			PermitWithoutStream: true, // Idle connections are the ones that silently die
+		}))
+	}
+
+	if cqc.config.IdleTimeout > 0 {
+		opts = append(opts, grpc.WithIdleTimeout(cqc.config.IdleTimeout))
+	}
+
+	var callOpts []grpc.CallOption
+	if cqc.config.MaxRecvMsgSize > 0 {
+		callOpts = append(callOpts, grpc.MaxCallRecvMsgSize(cqc.config.MaxRecvMsgSize))
+	}
+	if cqc.config.MaxSendMsgSize > 0 {
+		callOpts = append(callOpts, grpc.MaxCallSendMsgSize(cqc.config.MaxSendMsgSize))
+	}
+	if cqc.config.UseCompression {
+		callOpts = append(callOpts, grpc.UseCompressor(gzip.Name))
+	}
+	if len(callOpts) > 0 {
+		opts = append(opts, grpc.WithDefaultCallOptions(callOpts...))
+	}
+
+	if cqc.config.EnableTracing {
+		opts = append(opts, cqc.tracingDialOption())
+	}
+
+	var interceptors []grpc.UnaryClientInterceptor
+	if cqc.config.AuthToken != "" {
+		interceptors = append(interceptors, bearerTokenInterceptor(cqc.config.AuthToken))
+	}
+	if cqc.config.MetadataFunc != nil {
+		interceptors = append(interceptors, metadataInterceptor(cqc.config.MetadataFunc))
+	}
+	interceptors = append(interceptors, cqc.config.Interceptors...)
+	if len(interceptors) > 0 {
+		opts = append(opts, grpc.WithChainUnaryInterceptor(interceptors...))
+	}
+
+	return append(opts, cqc.config.DialOptions...)
+}
+
+// waitForReady kicks the connection out of idle and blocks until it reports Ready,
+// the timeout elapses or ctx is cancelled
+func waitForReady(ctx context.Context, conn *grpc.ClientConn, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	conn.Connect()
+	for {
+		state := conn.GetState()
+		if state == connectivity.Ready {
+			return nil
+		}
+		if !conn.WaitForStateChange(ctx, state) {
+			return fmt.Errorf("connection timed out waiting to become ready, last state: %s", state.String())
+		}
+	}
+}
+
+// dial creates a connection to a single endpoint and verifies it is usable,
+// giving up once ConnectionTimeout elapses. With NonBlockingDial the connection is
+// returned unchecked.
+func (cqc *CosmosQueryClient) dial(ctx context.Context, endpoint string, dialOpts []grpc.DialOption) (*grpc.ClientConn, error) {
+	conn, err := grpc.NewClient(endpoint, dialOpts...)
+	if err != nil {
+		return nil, err
+	}
+	if cqc.config.NonBlockingDial {
+		conn.Connect()
+		return conn, nil
+	}
+
+	// Block until the connection is established or ConnectionTimeout elapses
+	if err := waitForReady(ctx, conn, cqc.config.ConnectionTimeout); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	if cqc.config.SkipVerification {
+		return conn, nil
+	}
+
+	// Verify connection is actually usable
+	if err := cqc.verifyConnection(ctx, conn, cqc.ContractAddr()); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("connection established but verification failed: %w", err)
+	}
+
+	return conn, nil
+}
+
+// connect attempts to establish a connection, retrying with the configured backoff and
+// giving up early if ctx is cancelled. Each attempt walks the configured endpoints
+// in order, starting from the one that last connected successfully
+func (cqc *CosmosQueryClient) connect(ctx context.Context) error {
+	creds, err := cqc.transportCredentials()
+	if err != nil {
+		return err
+	}
+	if cqc.config.EnableExpvar {
+		cqc.expvarOnce.Do(cqc.publishExpvar)
+	}
+
+	endpoints := cqc.config.Endpoints()
+	cqc.mu.RLock()
+	start := cqc.endpointIdx
+	level := cqc.backoffLevel
+	cqc.mu.RUnlock()
+	if start >= len(endpoints) {
+		start = 0
+	}
+
 	attempt := 0
+	started := time.Now()
 
 	for {
-		// Try to connect
-		log.Printf("Attempting to connect to gRPC at %s (attempt %d)", cqc.config.GrpcURL, attempt+1)
-		
-		// Create connection
-		conn, err := grpc.Dial(
-			cqc.config.GrpcURL,
-			grpc.WithTransportCredentials(insecure.NewCredentials()),
-			grpc.WithBlock(), // Makes Dial block until a connection is established
-			grpc.WithTimeout(cqc.config.ConnectionTimeout), // Timeout for initial connection
-		)
-		
-		if err == nil {
-			// Verify connection is actually usable
-			err = cqc.verifyConnection(conn)
+		failures := &MultiEndpointError{}
+		for i := range endpoints {
+			idx := (start + i) % len(endpoints)
+			endpoint := endpoints[idx]
+
+			// Try to connect
+			cqc.log().Info("Attempting to connect to gRPC", "grpc_url", endpoint, "attempt", attempt+1)
+
+			var conn *grpc.ClientConn
+			conn, err = cqc.dial(ctx, endpoint, cqc.dialOptions(endpoint, creds))
 			if err == nil {
-				// Connection successful and verified
+				// Connection successful and verified, swap it in and only then
+				// close the connection it replaces
+				cqc.mu.Lock()
+				if cqc.closed {
+					cqc.mu.Unlock()
+					conn.Close()
+					return ErrClientClosed
+				}
+				old := cqc.conn
 				cqc.conn = conn
 				cqc.queryClient = wasmtypes.NewQueryClient(conn)
-				log.Printf("Successfully connected to gRPC at %s", cqc.config.GrpcURL)
+				cqc.endpointIdx = idx
+				cqc.activeEndpoint = endpoint
+				cqc.backoffLevel = level + attempt
+				cqc.mu.Unlock()
+				if old != nil {
+					old.Close()
+				}
+				cqc.stats.lastConnected.Store(time.Now().UnixNano())
+				cqc.log().Info("Successfully connected to gRPC", "grpc_url", endpoint, "attempt", attempt+1)
 				return nil
 			}
-			// Connection verification failed, close it and retry
-			conn.Close()
-			log.Printf("Connection established but verification failed: %v", err)
+			cqc.log().Warn("Failed to connect to gRPC", "grpc_url", endpoint, "attempt", attempt+1, "error", err)
+			failures.Errors = append(failures.Errors, EndpointError{Endpoint: endpoint, Err: err})
+
+			if ctx.Err() != nil {
+				break
+			}
 		}
-		
+		err = failures
+
 		attempt++
-		
+
 		// Check if max retries reached (if not set to infinite)
 		if cqc.config.MaxRetries > 0 && attempt >= cqc.config.MaxRetries {
-			return fmt.Errorf("failed to connect to gRPC at %s after %d attempts: %v", 
-				cqc.config.GrpcURL, attempt, err)
+			return fmt.Errorf("%w: failed to connect to gRPC at %s after %d attempts: %w",
+				ErrConnectionFailed, strings.Join(endpoints, ", "), attempt, err)
+		}
+
+		// Check if the overall time budget is spent
+		elapsed := time.Since(started)
+		if cqc.config.MaxElapsedTime > 0 && elapsed >= cqc.config.MaxElapsedTime {
+			return fmt.Errorf("%w: failed to connect to gRPC at %s after %d attempts in %v (max elapsed time %v): %w",
+				ErrConnectionFailed, strings.Join(endpoints, ", "), attempt, elapsed.Round(time.Millisecond), cqc.config.MaxElapsedTime, err)
 		}
-		
-		// Calculate next backoff with exponential increase, but capped at max
-		backoff = time.Duration(math.Min(
-			float64(backoff)*2, 
-			float64(cqc.config.MaxBackoff),
-		))
-		
-		log.Printf("Connection failed: %v. Retrying in %v...", err, backoff)
-		time.Sleep(backoff)
+
+		sleep := cqc.jitter(cqc.backoff().NextBackoff(level + attempt))
+		if remaining := cqc.config.MaxElapsedTime - elapsed; cqc.config.MaxElapsedTime > 0 && sleep > remaining {
+			sleep = remaining
+		}
+		cqc.log().Warn("Connection failed, retrying", "attempt", attempt, "backoff", sleep, "error", err)
+		if err := sleepBackoff(ctx, sleep); err != nil {
+			return fmt.Errorf("%w: connecting to gRPC at %s aborted after %d attempts: %w",
+				ErrConnectionFailed, strings.Join(endpoints, ", "), attempt, err)
+		}
+	}
+}
+
+// jitter applies full jitter to a backoff when BackoffJitter is enabled, returning a
+// random duration in [0, backoff)
+func (cqc *CosmosQueryClient) jitter(backoff time.Duration) time.Duration {
+	if !cqc.config.BackoffJitter {
+		return backoff
 	}
+	return cqc.fullJitter(backoff)
 }
 
+// fullJitter returns a random duration in [0, backoff)
+func (cqc *CosmosQueryClient) fullJitter(backoff time.Duration) time.Duration {
+	if backoff <= 0 {
+		return backoff
+	}
+	cqc.randMu.Lock()
+	defer cqc.randMu.Unlock()
+	if cqc.rand == nil {
+		cqc.rand = rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+	return time.Duration(cqc.rand.Int63n(int64(backoff)))
+}
+
+// Close shuts the client down gracefully, see CloseContext. It blocks until
+// in-flight queries have finished, which QueryTimeout keeps bounded by default.
 func (cqc *CosmosQueryClient) Close() {
-	if cqc.conn != nil {
-		cqc.conn.Close()
+	if err := cqc.CloseContext(context.Background()); err != nil {
+		cqc.log().Warn("Closing cosmos query client failed", "error", err)
 	}
 }
 
+// CloseContext stops the health check and any reconnect it is running, waits for
+// in-flight queries to finish and then closes the connection. New queries fail
+// with ErrClientClosed as soon as it is called. If ctx is done before the queries
+// drain, the connection is closed anyway, aborting them, and ctx.Err() is
+// returned. Closing an already closed client is a no-op.
+func (cqc *CosmosQueryClient) CloseContext(ctx context.Context) error {
+	cqc.mu.Lock()
+	if cqc.closed {
+		cqc.mu.Unlock()
+		return nil
+	}
+	cqc.closed = true
+	stop := cqc.stopBackground
+	cqc.stopBackground = nil
+	if cqc.lazy != nil {
+		stop = append(stop, cqc.lazy.cancel)
+	}
+	cqc.mu.Unlock()
+
+	for _, cancel := range stop {
+		cancel()
+	}
+
+	drained := make(chan struct{})
+	go func() {
+		cqc.background.Wait()
+		cqc.inflight.Wait()
+		close(drained)
+	}()
+
+	var err error
+	select {
+	case <-drained:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	cqc.mu.Lock()
+	conn := cqc.conn
+	cqc.conn = nil
+	cqc.queryClient = nil
+	cqc.mu.Unlock()
+
+	if conn != nil {
+		if closeErr := conn.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
+
+// beginQuery registers an in-flight query so that CloseContext waits for it. The
+// returned function must be called once the query is done.
+func (cqc *CosmosQueryClient) beginQuery() (func(), error) {
+	cqc.mu.Lock()
+	defer cqc.mu.Unlock()
+	if cqc.closed {
+		return nil, ErrClientClosed
+	}
+	cqc.inflight.Add(1)
+	return cqc.inflight.Done, nil
+}
+
+// currentQueryClient returns the query client for the live connection, or a clear
+// error when there is none instead of letting callers dereference a nil client.
+// Callers use the returned snapshot without holding the lock, so a concurrent
+// reconnect never blocks on a query in flight.
+func (cqc *CosmosQueryClient) currentQueryClient() (wasmtypes.QueryClient, error) {
+	cqc.mu.RLock()
+	defer cqc.mu.RUnlock()
+	if cqc.queryClient == nil {
+		if cqc.closed {
+			return nil, ErrClientClosed
+		}
+		if cqc.reconnecting {
+			return nil, ErrReconnecting
+		}
+		return nil, ErrNotConnected
+	}
+	return cqc.queryClient, nil
+}
+
 func (cqc *CosmosQueryClient) GetMerkleTreeData(id string) (*MerkleTree, error) {
-	query := QueryGetTree{}
-	query.GetMerkleTree.ID = id
+	return cqc.GetMerkleTreeDataContext(context.Background(), id)
+}
+
+// GetMerkleTreeDataContext fetches the merkle tree with the given ID. When caching
+// is enabled a cached copy is returned without contacting the contract. Concurrent
+// calls for the same ID share a single query; ctx only bounds how long this caller
+// waits for it, the shared query itself is bounded by QueryTimeout.
+func (cqc *CosmosQueryClient) GetMerkleTreeDataContext(ctx context.Context, id string) (*MerkleTree, error) {
+	tree, _, err := cqc.GetMerkleTreeDataCached(ctx, id)
+	return tree, err
+}
+
+// GetMerkleTreeDataCached is GetMerkleTreeDataContext that also reports whether the
+// tree is stale: served from a cache entry past CacheTTL, with a refresh started
+// in the background. Only CacheStaleWhileRevalidate returns stale trees.
+func (cqc *CosmosQueryClient) GetMerkleTreeDataCached(ctx context.Context, id string) (tree *MerkleTree, stale bool, err error) {
+	ctx, end := cqc.startSpan(ctx, "GetMerkleTreeData", attribute.String("tree.id", id))
+	defer func() { end(err) }()
+
+	cache := cqc.treeCache()
+	if cache != nil {
+		if tree, stale, ok := cache.get(id); ok {
+			if stale {
+				cqc.revalidate(ctx, cache, id)
+			}
+			return tree, stale, nil
+		}
+		if err := cache.getMissing(id); err != nil {
+			return nil, false, err
+		}
+	}
 
-	queryBytes, err := json.Marshal(query)
+	select {
+	case <-ctx.Done():
+		return nil, false, ctx.Err()
+	case res := <-cqc.fetchShared(ctx, cache, id):
+		if res.Err != nil {
+			return nil, false, res.Err
+		}
+		// Every caller gets its own copy so none can mutate another's tree
+		return res.Val.(*MerkleTree).clone(), false, nil
+	}
+}
+
+// revalidate refetches a stale tree in the background unless a refresh of it is
+// already running. A failed refresh leaves the stale tree in place.
+func (cqc *CosmosQueryClient) revalidate(ctx context.Context, cache *treeCache, id string) {
+	if !cache.beginRefresh(id) {
+		return
+	}
+	ch := cqc.fetchShared(ctx, cache, id)
+	go func() {
+		defer cache.endRefresh(id)
+		if res := <-ch; res.Err != nil {
+			cqc.log().Warn("Failed to refresh stale cached tree", "tree_id", id, "error", res.Err)
+		}
+	}()
+}
+
+// fetchMerkleTree queries the contract at addr for the tree with the given ID
+func (cqc *CosmosQueryClient) fetchMerkleTree(ctx context.Context, addr, id string, opts ...grpc.CallOption) (*MerkleTree, error) {
+	var header metadata.MD
+	data, err := cqc.getMerkleTreeDataRaw(ctx, addr, id, append(opts, grpc.Header(&header))...)
+	if err != nil {
+		return nil, err
+	}
+	tree, err := cqc.decodeMerkleTree(id, data)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal query: %v", err)
+		return nil, err
 	}
+	tree.Height = blockHeightFromHeader(header)
+	return tree, nil
+}
 
-	res, err := cqc.queryClient.SmartContractState(
-		context.Background(),
-		&wasmtypes.QuerySmartContractStateRequest{
-			Address:   cqc.config.ContractAddr,
-			QueryData: queryBytes,
-		},
-	)
+// decodeMerkleTree decodes a get_merkle_tree response and applies the configured
+// validation and deduplication
+func (cqc *CosmosQueryClient) decodeMerkleTree(id string, data []byte) (*MerkleTree, error) {
+	// The tree is nil when the contract answers null
+	tree, err := cqc.decodeTree(unwrapDataEnvelope(data), cqc.config.MaxLeaves, nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query contract: %v", err)
+		return nil, fmt.Errorf("failed to decode merkle tree %s: %w", id, err)
+	}
+	if tree == nil {
+		return nil, fmt.Errorf("%w: %s", ErrTreeNotFound, id)
 	}
 
-	// Parse response JSON into struct
-	var tree MerkleTree
-	err = json.Unmarshal(res.Data, &tree)
+	if cqc.config.StrictLeaves {
+		if err := tree.ValidateLeaves(); err != nil {
+			return nil, fmt.Errorf("tree %s failed validation: %w", id, err)
+		}
+	}
+	if cqc.config.VerifyRoot {
+		if err := cqc.config.Merkle.ValidateTree(tree); err != nil {
+			return nil, fmt.Errorf("tree %s failed validation: %w", id, err)
+		}
+	}
+	if cqc.config.DedupLeaves {
+		tree = cqc.dedupLeaves(id, tree)
+	}
+
+	return tree, nil
+}
+
+// dedupLeaves removes repeated leaves from tree, warning when that leaves a tree
+// whose leaves no longer hash to its root
+func (cqc *CosmosQueryClient) dedupLeaves(id string, tree *MerkleTree) *MerkleTree {
+	deduped := tree.DedupLeaves()
+	if len(deduped.Leaves) == len(tree.Leaves) {
+		return tree
+	}
+
+	log := cqc.log().With("tree_id", id, "leaves", len(tree.Leaves), "unique_leaves", len(deduped.Leaves))
+	if root, err := cqc.config.Merkle.ComputeRoot(deduped.Leaves); err == nil && root != NormalizeHex(tree.Root) {
+		log.Warn("Removed duplicate leaves, the remaining leaves no longer hash to the tree's root", "root", tree.Root, "deduped_root", root)
+	} else {
+		log.Info("Removed duplicate leaves")
+	}
+	return deduped
+}
+
+// Exists reports whether the contract has a tree with the given ID. The contract
+// has no lighter query than get_merkle_tree, so the tree is still fetched, but it
+// is neither validated nor cached, and a not-found answer is reported as false
+// rather than as an error. Cached trees are answered without a query.
+func (cqc *CosmosQueryClient) Exists(ctx context.Context, id string) (bool, error) {
+	if cache := cqc.treeCache(); cache != nil {
+		if _, _, ok := cache.get(id); ok {
+			return true, nil
+		}
+	}
+
+	query := QueryGetTree{}
+	query.GetMerkleTree.ID = id
+
+	data, err := cqc.SmartContractRaw(ctx, query)
 	if err != nil {
-		return nil, fmt.Errorf("failed to unmarshal tree data: %v", err)
+		if isNotFoundError(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to check merkle tree %s: %w", id, err)
+	}
+
+	// Leaves are skipped rather than decoded, only null matters
+	tree, err := cqc.decodeTree(unwrapDataEnvelope(data), 0, func(string) error { return nil })
+	if err != nil {
+		return false, fmt.Errorf("failed to check merkle tree %s: %w", id, err)
 	}
+	return tree != nil, nil
+}
 
-	return &tree, nil
+// GetMerkleTreeDataRaw returns the contract's JSON response for the tree with the
+// given ID untouched, bypassing the cache and any validation. A missing tree wraps
+// ErrTreeNotFound as it does for GetMerkleTreeData.
+func (cqc *CosmosQueryClient) GetMerkleTreeDataRaw(ctx context.Context, id string) ([]byte, error) {
+	return cqc.getMerkleTreeDataRaw(ctx, cqc.ContractAddr(), id)
 }
 
-func (cqc *CosmosQueryClient) ListMerkleTreeIds() ([]string, error) {
-	query := QueryListTreeIDs{}
+func (cqc *CosmosQueryClient) getMerkleTreeDataRaw(ctx context.Context, addr, id string, opts ...grpc.CallOption) ([]byte, error) {
+	query := QueryGetTree{}
+	query.GetMerkleTree.ID = id
 
-	queryBytes, err := json.Marshal(query)
+	data, err := cqc.smartContractRaw(ctx, addr, query, opts...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal query: %v", err)
+		if isNotFoundError(err) {
+			return nil, fmt.Errorf("%w: %s: %w", ErrTreeNotFound, id, err)
+		}
+		return nil, fmt.Errorf("failed to get merkle tree %s: %w", id, err)
 	}
+	return data, nil
+}
 
-	res, err := cqc.queryClient.SmartContractState(
-		context.Background(),
-		&wasmtypes.QuerySmartContractStateRequest{
-			Address:   cqc.config.ContractAddr,
-			QueryData: queryBytes,
-		},
-	)
+// ListMerkleTreeIdsRaw returns the contract's JSON response listing the tree IDs
+// untouched
+func (cqc *CosmosQueryClient) ListMerkleTreeIdsRaw(ctx context.Context) ([]byte, error) {
+	data, err := cqc.SmartContractRaw(ctx, QueryListTreeIDs{})
 	if err != nil {
-		return nil, fmt.Errorf("failed to query contract: %v", err)
+		return nil, fmt.Errorf("failed to list merkle tree ids: %w", err)
 	}
+	return data, nil
+}
+
+func (cqc *CosmosQueryClient) ListMerkleTreeIds() ([]string, error) {
+	return cqc.ListMerkleTreeIdsContext(context.Background())
+}
+
+// ListMerkleTreeIdsContext lists the IDs of all merkle trees stored in the contract,
+// using ctx for cancellation and deadlines of the underlying gRPC call
+func (cqc *CosmosQueryClient) ListMerkleTreeIdsContext(ctx context.Context) (ids []string, err error) {
+	ctx, end := cqc.startSpan(ctx, "ListMerkleTreeIds")
+	defer func() { end(err) }()
 
-	// Parse response JSON into struct
-	var treeIds []string
-	err = json.Unmarshal(res.Data, &treeIds)
+	data, err := cqc.ListMerkleTreeIdsRaw(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to unmarshal tree data: %v", err)
+		return nil, err
 	}
-	return treeIds, nil
-}
\ No newline at end of file
+	ids, err = cqc.decodeIds(unwrapDataEnvelope(data), cqc.config.MaxLeaves)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode merkle tree ids: %w", err)
+	}
+	return ids, nil
+}
@@ -0,0 +1,91 @@
+package clients_test
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/Layer-Edge/light-node/clients"
+)
+
+// serveFixtures answers get_merkle_tree with the tree fixture and
+// list_merkle_tree_ids with the ID fixture, both read from testdata
+func serveFixtures(t *testing.T, treeFile, idsFile string) *clients.CosmosQueryClient {
+	t.Helper()
+	tree, err := os.ReadFile(filepath.Join("testdata", treeFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids, err := os.ReadFile(filepath.Join("testdata", idsFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server, client := newFakeClient(t)
+	server.SetSmartQueryHandler(func(name string, _ json.RawMessage) ([]byte, error) {
+		if name == "list_merkle_tree_ids" {
+			return ids, nil
+		}
+		return tree, nil
+	})
+	return client
+}
+
+func TestResponseShapes(t *testing.T) {
+	want := &clients.MerkleTree{
+		Root:     "65859a46039b52815c5fb014d30a6d190ac27ed8920acb46086de8bcae3229a0",
+		Leaves:   []string{"apple", "banana", "cherry", "date", "elderberry"},
+		Metadata: "source=risc0",
+	}
+	wantIds := []string{"tree-1", "tree-2", "tree-3"}
+
+	for _, shape := range []string{"flat", "envelope"} {
+		t.Run(shape, func(t *testing.T) {
+			client := serveFixtures(t, "tree_"+shape+".json", "ids_"+shape+".json")
+			ctx := context.Background()
+
+			tree, err := client.GetMerkleTreeDataContext(ctx, "tree-1")
+			if err != nil {
+				t.Fatalf("GetMerkleTreeDataContext: %v", err)
+			}
+			if !reflect.DeepEqual(tree, want) {
+				t.Errorf("GetMerkleTreeDataContext = %+v, want %+v", tree, want)
+			}
+
+			var leaves []string
+			streamed, err := client.StreamMerkleTree(ctx, "tree-1", func(leaf string) error {
+				leaves = append(leaves, leaf)
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("StreamMerkleTree: %v", err)
+			}
+			if streamed.Root != want.Root || streamed.Metadata != want.Metadata || !reflect.DeepEqual(leaves, want.Leaves) {
+				t.Errorf("StreamMerkleTree = %+v with leaves %v, want %+v", streamed, leaves, want)
+			}
+
+			ids, err := client.ListMerkleTreeIdsContext(ctx)
+			if err != nil {
+				t.Fatalf("ListMerkleTreeIdsContext: %v", err)
+			}
+			if !reflect.DeepEqual(ids, wantIds) {
+				t.Errorf("ListMerkleTreeIdsContext = %v, want %v", ids, wantIds)
+			}
+
+			ids = nil
+			err = client.StreamMerkleTreeIds(ctx, func(id string) error {
+				ids = append(ids, id)
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("StreamMerkleTreeIds: %v", err)
+			}
+			if !reflect.DeepEqual(ids, wantIds) {
+				t.Errorf("StreamMerkleTreeIds = %v, want %v", ids, wantIds)
+			}
+		})
+	}
+}
@@ -0,0 +1,157 @@
+package clients
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Sentinel errors returned (wrapped) by the clients package; match them with errors.Is
+var (
+	// ErrInvalidConfig is returned when a ClientConfig fails validation
+	ErrInvalidConfig = errors.New("invalid client configuration")
+	// ErrConnectionFailed is returned when no usable gRPC connection could be established
+	ErrConnectionFailed = errors.New("gRPC connection failed")
+	// ErrTreeNotFound is returned when the contract has no merkle tree with the requested ID
+	ErrTreeNotFound = errors.New("merkle tree not found")
+	// ErrInvalidResponse is returned when the contract response cannot be decoded
+	ErrInvalidResponse = errors.New("invalid contract response")
+	// ErrEmptyResponse is returned when the contract answers a query with no data at all
+	ErrEmptyResponse = errors.New("empty contract response")
+	// ErrNotConnected is returned by queries issued before the client has connected
+	ErrNotConnected = errors.New("cosmos query client is not connected")
+	// ErrReconnecting is returned by queries issued while the connection is being rebuilt
+	ErrReconnecting = errors.New("cosmos query client is reconnecting")
+	// ErrLeafNotFound is returned when a leaf is not part of the merkle tree
+	ErrLeafNotFound = errors.New("leaf not found in merkle tree")
+	// ErrInvalidProof is returned when a merkle proof or root is malformed
+	ErrInvalidProof = errors.New("invalid merkle proof")
+	// ErrRootMismatch is returned when a tree's leaves do not hash to its root
+	ErrRootMismatch = errors.New("merkle root mismatch")
+	// ErrInvalidLeaf is returned when a tree's leaves are not well-formed hex digests
+	ErrInvalidLeaf = errors.New("invalid merkle leaf")
+	// ErrInvalidMetadata is returned when a tree's metadata cannot be parsed
+	ErrInvalidMetadata = errors.New("invalid merkle tree metadata")
+	// ErrCircuitOpen is returned without contacting the node while the circuit breaker is open
+	ErrCircuitOpen = errors.New("circuit breaker is open")
+	// ErrNodeUnreachable is returned by Ping when the gRPC node does not answer or is not serving
+	ErrNodeUnreachable = errors.New("gRPC node unreachable")
+	// ErrContractNotFound is returned by PingContract when the node has no contract at ContractAddr
+	ErrContractNotFound = errors.New("contract not found")
+	// ErrClientClosed is returned by queries issued after Close or CloseContext
+	ErrClientClosed = errors.New("cosmos query client is closed")
+	// ErrNodeLagging is returned when a node answers from a block too far behind ReferenceHeight
+	ErrNodeLagging = errors.New("node is lagging behind the chain")
+	// ErrNoQuorum is returned by GetMerkleTreeDataQuorum when too few endpoints agree on a tree's root
+	ErrNoQuorum = errors.New("no quorum")
+	// ErrPanic wraps a panic recovered in one of the client's background goroutines
+	ErrPanic = errors.New("recovered panic")
+)
+
+// EndpointError is the failure of one gRPC endpoint, to connect or to answer a query
+type EndpointError struct {
+	Endpoint string
+	Err      error
+}
+
+func (e EndpointError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Endpoint, e.Err)
+}
+
+func (e EndpointError) Unwrap() error {
+	return e.Err
+}
+
+// MultiEndpointError collects why each endpoint failed during one pass over the
+// configured endpoints, in the order they were tried. Connection errors wrap it, so
+// errors.As finds it and errors.Is matches any endpoint's error.
+type MultiEndpointError struct {
+	Errors []EndpointError
+}
+
+func (e *MultiEndpointError) Error() string {
+	parts := make([]string, 0, len(e.Errors))
+	for _, err := range e.Errors {
+		parts = append(parts, err.Error())
+	}
+	return fmt.Sprintf("%d endpoint(s) failed: %s", len(e.Errors), strings.Join(parts, "; "))
+}
+
+func (e *MultiEndpointError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Errors))
+	for _, err := range e.Errors {
+		errs = append(errs, err)
+	}
+	return errs
+}
+
+// QueryError is returned when a contract query fails. It keeps the gRPC status of
+// the failure so callers can branch on its code with errors.As, or directly with
+// status.Code, which understands the GRPCStatus method.
+type QueryError struct {
+	ContractAddr string
+	RequestID    string         // Sent as x-request-id when SendRequestID is enabled
+	Status       *status.Status // Nil when the failure did not come from the node, e.g. ErrCircuitOpen
+	Err          error
+}
+
+func newQueryError(contractAddr string, err error) *QueryError {
+	qe := &QueryError{ContractAddr: contractAddr, Err: err}
+	if st, ok := status.FromError(err); ok {
+		qe.Status = st
+	}
+	return qe
+}
+
+func (e *QueryError) Error() string {
+	if e.RequestID != "" {
+		return fmt.Sprintf("failed to query contract %s (request id %s): %v", e.ContractAddr, e.RequestID, e.Err)
+	}
+	return fmt.Sprintf("failed to query contract %s: %v", e.ContractAddr, e.Err)
+}
+
+func (e *QueryError) Unwrap() error {
+	return e.Err
+}
+
+// Code returns the gRPC status code of the failure, or codes.Unknown when it did
+// not come from the node
+func (e *QueryError) Code() codes.Code {
+	if e.Status == nil {
+		return codes.Unknown
+	}
+	return e.Status.Code()
+}
+
+// GRPCStatus exposes the status to status.FromError and status.Code
+func (e *QueryError) GRPCStatus() *status.Status {
+	return e.Status
+}
+
+// isNotFoundError reports whether a SmartContractState error is the contract
+// saying the requested item does not exist, as opposed to a transport failure.
+// Contract errors surface as a generic status whose message carries the
+// contract's own error text, so the message is inspected as well as the code.
+func isNotFoundError(err error) bool {
+	st, ok := status.FromError(err)
+	if !ok {
+		return false
+	}
+	if st.Code() == codes.NotFound {
+		return true
+	}
+	return st.Code() == codes.Unknown && strings.Contains(strings.ToLower(st.Message()), "not found")
+}
+
+// isNoSuchContractError reports whether a ContractInfo error says there is no
+// contract at the queried address. wasmd reports this as "no such contract".
+func isNoSuchContractError(err error) bool {
+	if isNotFoundError(err) {
+		return true
+	}
+	st, ok := status.FromError(err)
+	return ok && strings.Contains(strings.ToLower(st.Message()), "no such contract")
+}
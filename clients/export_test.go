@@ -0,0 +1,9 @@
+package clients
+
+import "context"
+
+// Reconnect rebuilds the connection as the health check does, for tests that need
+// to force a reconnect
+func (cqc *CosmosQueryClient) Reconnect(ctx context.Context) error {
+	return cqc.reconnect(ctx)
+}
@@ -0,0 +1,26 @@
+package clients_test
+
+import (
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/Layer-Edge/light-node/clients"
+	"github.com/Layer-Edge/light-node/clients/internal/clientstest"
+)
+
+// newFakeClient starts a FakeQueryServer and a client connected to it that logs
+// nothing, both closed when the test ends
+func newFakeClient(tb testing.TB, opts ...clients.Option) (*clientstest.FakeQueryServer, *clients.CosmosQueryClient) {
+	tb.Helper()
+	server := clientstest.NewFakeQueryServer()
+	tb.Cleanup(server.Close)
+
+	opts = append([]clients.Option{clients.WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil)))}, opts...)
+	client, err := server.NewClient(opts...)
+	if err != nil {
+		tb.Fatalf("NewClient: %v", err)
+	}
+	tb.Cleanup(client.Close)
+	return server, client
+}
@@ -0,0 +1,47 @@
+package clients
+
+import (
+	"context"
+	"errors"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// fetchShared fetches the tree with the given ID, sharing the query with any
+// other fetch of it in flight, and caches the outcome. The shared query is
+// detached from the callers' cancellation, so that one caller giving up does not
+// fail everyone else waiting on it, and is bounded by QueryTimeout instead; each
+// caller waits for it only as long as its own ctx allows.
+func (cqc *CosmosQueryClient) fetchShared(ctx context.Context, cache *treeCache, id string) <-chan singleflight.Result {
+	// Values such as the trace span come from the caller that starts the fetch
+	shared := context.WithoutCancel(ctx)
+	// Keyed by contract so a fetch from before UpdateContractAddr is not shared
+	// with, or cached for, the new contract
+	addr := cqc.ContractAddr()
+	return cqc.flight.DoChan(addr+"/"+id, func() (any, error) {
+		ctx := shared
+		if cqc.config.QueryTimeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, cqc.config.QueryTimeout)
+			defer cancel()
+		}
+
+		tree, err := cqc.fetchMerkleTree(ctx, addr, id)
+		if err == nil {
+			err = cqc.checkBlockLag(ctx, id, tree.Height)
+		}
+		if cache != nil && cqc.ContractAddr() != addr {
+			cache = nil
+		}
+		if err != nil {
+			if cache != nil && errors.Is(err, ErrTreeNotFound) {
+				cache.putMissing(id, err)
+			}
+			return nil, err
+		}
+		if cache != nil {
+			cache.put(id, tree)
+		}
+		return tree, nil
+	})
+}
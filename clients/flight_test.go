@@ -0,0 +1,66 @@
+package clients_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Layer-Edge/light-node/clients"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestSharedFetch(t *testing.T) {
+	server, client := newFakeClient(t, clients.WithTimeouts(time.Second, 200*time.Millisecond))
+	started := make(chan struct{}, 1)
+	release := make(chan struct{})
+	server.SetSmartQueryHandler(func(string, json.RawMessage) ([]byte, error) {
+		started <- struct{}{}
+		select {
+		case <-release:
+			return json.Marshal(risc0Tree)
+		case <-time.After(time.Second):
+			return nil, status.Error(codes.Unavailable, "never released")
+		}
+	})
+
+	patient := make(chan error, 1)
+	go func() {
+		tree, err := client.GetMerkleTreeDataContext(context.Background(), "tree-1")
+		if err == nil && tree.Root != risc0Tree.Root {
+			err = errors.New("wrong root " + tree.Root)
+		}
+		patient <- err
+	}()
+	<-started
+
+	// A caller giving up leaves the shared query running for the other
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := client.GetMerkleTreeDataContext(ctx, "tree-1"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("impatient GetMerkleTreeDataContext error = %v, want DeadlineExceeded", err)
+	}
+	close(release)
+	if err := <-patient; err != nil {
+		t.Fatalf("patient GetMerkleTreeDataContext: %v", err)
+	}
+	if got := server.SmartQueries(); got != 1 {
+		t.Errorf("server saw %d smart queries, want the two callers to share 1", got)
+	}
+
+	// With nobody releasing it, QueryTimeout ends the shared query even for a
+	// caller without a deadline
+	server.SetSmartQueryHandler(func(string, json.RawMessage) ([]byte, error) {
+		time.Sleep(time.Second)
+		return json.Marshal(risc0Tree)
+	})
+	begin := time.Now()
+	if _, err := client.GetMerkleTreeDataContext(context.Background(), "tree-1"); status.Code(err) != codes.DeadlineExceeded {
+		t.Errorf("GetMerkleTreeDataContext error = %v, want DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(begin); elapsed > 900*time.Millisecond {
+		t.Errorf("GetMerkleTreeDataContext took %v, want QueryTimeout to end it after 200ms", elapsed)
+	}
+}
@@ -0,0 +1,366 @@
+package clients
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	wasmtypes "github.com/CosmWasm/wasmd/x/wasm/types"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/connectivity"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+// StartHealthCheck launches a background goroutine that periodically inspects the
+// connection state and rebuilds the connection once it has been unhealthy (neither
+// Ready nor Idle) for longer than UnhealthyThreshold. The goroutine exits when ctx
+// is cancelled or the client is closed. A panic in the loop is logged and the loop
+// restarted after a backoff, starting at HealthCheckInterval and doubling with each
+// panic in a row.
+func (cqc *CosmosQueryClient) StartHealthCheck(ctx context.Context) {
+	ctx, cancel := context.WithCancel(ctx)
+
+	cqc.mu.Lock()
+	defer cqc.mu.Unlock()
+	if cqc.closed {
+		cancel()
+		return
+	}
+	cqc.stopBackground = append(cqc.stopBackground, cancel)
+	cqc.background.Add(1)
+
+	go func() {
+		defer cqc.background.Done()
+		defer cancel()
+
+		// healthCheckLoop only returns before ctx is done by panicking. A loop that
+		// panics every time is restarted ever more slowly rather than spinning, and
+		// one that ran for a long time before panicking starts over from one interval.
+		delay := cqc.healthCheckInterval()
+		for {
+			started := time.Now()
+			err := cqc.runRecovered("health check", func() { cqc.healthCheckLoop(ctx) })
+			if err == nil || ctx.Err() != nil {
+				return
+			}
+			if time.Since(started) >= maxHealthCheckRestartBackoff {
+				delay = cqc.healthCheckInterval()
+			}
+			cqc.log().Warn("Restarting health check after a panic", "backoff", delay)
+			if sleepContext(ctx, delay) != nil {
+				return
+			}
+			delay = min(delay*2, maxHealthCheckRestartBackoff)
+		}
+	}()
+}
+
+// defaultHealthCheckInterval stands in for a HealthCheckInterval left at 0
+const defaultHealthCheckInterval = 15 * time.Second
+
+// maxHealthCheckRestartBackoff caps the wait before restarting a health check loop
+// that panicked
+const maxHealthCheckRestartBackoff = 5 * time.Minute
+
+// healthCheckInterval returns how often the health check inspects the connection
+func (cqc *CosmosQueryClient) healthCheckInterval() time.Duration {
+	if cqc.config.HealthCheckInterval <= 0 {
+		return defaultHealthCheckInterval
+	}
+	return cqc.config.HealthCheckInterval
+}
+
+func (cqc *CosmosQueryClient) healthCheckLoop(ctx context.Context) {
+	ticker := time.NewTicker(cqc.healthCheckInterval())
+	defer ticker.Stop()
+
+	var unhealthySince, readySince time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		cqc.mu.RLock()
+		conn := cqc.conn
+		cqc.mu.RUnlock()
+
+		if conn != nil {
+			state := conn.GetState()
+			if state == connectivity.Ready || state == connectivity.Idle {
+				unhealthySince = time.Time{}
+				if state != connectivity.Ready {
+					readySince = time.Time{}
+				} else if readySince.IsZero() {
+					readySince = time.Now()
+				} else if time.Since(readySince) >= cqc.backoffResetAfter() {
+					cqc.resetBackoff()
+				}
+				continue
+			}
+			readySince = time.Time{}
+			if unhealthySince.IsZero() {
+				cqc.log().Warn("gRPC connection unhealthy", "state", state.String(),
+					"unhealthy_threshold", cqc.config.UnhealthyThreshold)
+			}
+		}
+
+		if unhealthySince.IsZero() {
+			unhealthySince = time.Now()
+		}
+		if time.Since(unhealthySince) < cqc.config.UnhealthyThreshold {
+			continue
+		}
+
+		if err := cqc.reconnectShared(ctx, conn, "Reconnecting unhealthy gRPC connection", "unhealthy_since", unhealthySince); err != nil {
+			cqc.log().Error("Reconnect failed", "error", err)
+			continue
+		}
+		unhealthySince = time.Time{}
+	}
+}
+
+// backoffResetAfter returns how long the connection must stay Ready before the
+// carried-over backoff is reset
+func (cqc *CosmosQueryClient) backoffResetAfter() time.Duration {
+	if cqc.config.BackoffResetAfter > 0 {
+		return cqc.config.BackoffResetAfter
+	}
+	return 2 * cqc.config.MaxBackoff
+}
+
+// resetBackoff makes the next reconnect start again from the first backoff step
+func (cqc *CosmosQueryClient) resetBackoff() {
+	cqc.mu.Lock()
+	defer cqc.mu.Unlock()
+	if cqc.backoffLevel > 0 {
+		cqc.log().Debug("Connection stable, resetting backoff", "backoff_level", cqc.backoffLevel)
+		cqc.backoffLevel = 0
+	}
+}
+
+// reconnect establishes a new connection and swaps it in place of the current
+// one, which keeps serving queries until the swap. The old connection is closed
+// once the new one is installed, and queries it cuts off are repeated on the new
+// one.
+func (cqc *CosmosQueryClient) reconnect(ctx context.Context) error {
+	cqc.mu.Lock()
+	if cqc.closed {
+		cqc.mu.Unlock()
+		return ErrClientClosed
+	}
+	cqc.reconnecting = true
+	cqc.mu.Unlock()
+
+	err := cqc.connect(ctx)
+	if err == nil {
+		cqc.stats.reconnects.Add(1)
+	}
+
+	cqc.mu.Lock()
+	cqc.reconnecting = false
+	cqc.mu.Unlock()
+
+	return err
+}
+
+// reconnectDead rebuilds the connection right away when a query failed with err
+// because the connection is dead, rather than waiting for the health check, and
+// reports whether a fresh connection is now in place. Concurrent callers share one
+// reconnect, which is bounded by ConnectionTimeout for each endpoint.
+func (cqc *CosmosQueryClient) reconnectDead(ctx context.Context, err error) bool {
+	cqc.mu.RLock()
+	dead := cqc.conn
+	cqc.mu.RUnlock()
+	if dead == nil || ctx.Err() != nil {
+		return false
+	}
+	if status.Code(err) != codes.Unavailable && dead.GetState() != connectivity.TransientFailure {
+		return false
+	}
+
+	timeout := cqc.config.ConnectionTimeout * time.Duration(len(cqc.config.Endpoints()))
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	if err := cqc.reconnectShared(ctx, dead, "Query found the connection dead, reconnecting"); err != nil {
+		cqc.log().Warn("Reconnect after a failed query failed", "error", err)
+		return false
+	}
+	return true
+}
+
+// reconnectShared replaces dead, the connection the caller found unusable, logging
+// msg with args first. The health check and failing queries share one reconnect
+// at a time, so they never dial and swap connections over each other, and it does
+// nothing once dead has already been replaced.
+func (cqc *CosmosQueryClient) reconnectShared(ctx context.Context, dead *grpc.ClientConn, msg string, args ...any) error {
+	_, err, _ := cqc.reconnFlight.Do("reconnect", func() (any, error) {
+		cqc.mu.RLock()
+		current := cqc.conn
+		cqc.mu.RUnlock()
+		if current != dead {
+			// Someone else already replaced the dead connection
+			return nil, nil
+		}
+		cqc.log().Warn(msg, append([]any{"grpc_url", cqc.ActiveEndpoint()}, args...)...)
+		return nil, cqc.reconnect(ctx)
+	})
+	return err
+}
+
+// superseded reports whether a query over queryClient failed with err only because
+// a reconnect replaced its connection and closed it while the call was running.
+// The connection that replaced it is live, so the query can be repeated on it.
+func (cqc *CosmosQueryClient) superseded(ctx context.Context, queryClient wasmtypes.QueryClient, err error) bool {
+	if queryClient == nil || ctx.Err() != nil || status.Code(err) != codes.Canceled {
+		return false
+	}
+	current, err := cqc.currentQueryClient()
+	return err == nil && current != queryClient
+}
+
+// ConnectionState reports the state of the current gRPC connection, or Shutdown
+// when the client has no connection
+func (cqc *CosmosQueryClient) ConnectionState() connectivity.State {
+	cqc.mu.RLock()
+	conn := cqc.conn
+	cqc.mu.RUnlock()
+
+	if conn == nil {
+		return connectivity.Shutdown
+	}
+	return conn.GetState()
+}
+
+// ActiveEndpoint returns the gRPC endpoint of the live connection, which changes
+// when a reconnect fails over to another endpoint, or "" when there is none
+func (cqc *CosmosQueryClient) ActiveEndpoint() string {
+	cqc.mu.RLock()
+	defer cqc.mu.RUnlock()
+	if cqc.conn == nil {
+		return ""
+	}
+	return cqc.activeEndpoint
+}
+
+// ContractAddr returns the address of the contract the client queries
+func (cqc *CosmosQueryClient) ContractAddr() string {
+	cqc.mu.RLock()
+	defer cqc.mu.RUnlock()
+	return cqc.config.ContractAddr
+}
+
+// UpdateContractAddr points the client at the contract at addr, for migrating to
+// a redeployed contract without a restart. Unless SkipVerification is set, the
+// live connection is first verified against the new address with VerifyStrategy,
+// and the old address is kept if that fails; a client without a connection
+// verifies the new address when it connects. Queries that start afterwards target
+// the new contract, while queries already running finish against the old one,
+// and cached trees are dropped.
+func (cqc *CosmosQueryClient) UpdateContractAddr(addr string) error {
+	if err := validateContractAddr(addr); err != nil {
+		return err
+	}
+
+	cqc.mu.RLock()
+	conn, old, closed := cqc.conn, cqc.config.ContractAddr, cqc.closed
+	cqc.mu.RUnlock()
+	if closed {
+		return ErrClientClosed
+	}
+	if addr == old {
+		return nil
+	}
+
+	if conn != nil && !cqc.config.SkipVerification {
+		if err := cqc.verifyConnection(context.Background(), conn, addr); err != nil {
+			return fmt.Errorf("contract %s failed verification, still using %s: %w", addr, old, err)
+		}
+	}
+
+	cqc.mu.Lock()
+	cqc.config.ContractAddr = addr
+	cqc.mu.Unlock()
+
+	// Contract info is cached per address, so it needs no reset
+	if cache := cqc.treeCache(); cache != nil {
+		cache.clear()
+	}
+
+	cqc.log().Info("Switched contract address", "old_contract_addr", old, "contract_addr", addr)
+	return nil
+}
+
+// IsConnected reports whether the client has a connection that is Ready, or Idle
+// and able to reconnect on the next call
+func (cqc *CosmosQueryClient) IsConnected() bool {
+	state := cqc.ConnectionState()
+	return state == connectivity.Ready || state == connectivity.Idle
+}
+
+// Ping checks that the gRPC node is reachable without involving the contract. It
+// calls the standard grpc.health.v1 Health/Check service and, on nodes that do not
+// implement it, falls back to the connection state. Failures wrap
+// ErrNodeUnreachable. When ctx has no deadline the check is bounded by
+// ConnectionTimeout.
+func (cqc *CosmosQueryClient) Ping(ctx context.Context) error {
+	cqc.mu.RLock()
+	conn := cqc.conn
+	cqc.mu.RUnlock()
+	if conn == nil {
+		return fmt.Errorf("%w: %w", ErrNodeUnreachable, ErrNotConnected)
+	}
+
+	if _, hasDeadline := ctx.Deadline(); !hasDeadline {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cqc.config.ConnectionTimeout)
+		defer cancel()
+	}
+
+	res, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{})
+	switch {
+	case status.Code(err) == codes.Unimplemented:
+		if err := waitForReady(ctx, conn, cqc.config.ConnectionTimeout); err != nil {
+			return fmt.Errorf("%w: %v", ErrNodeUnreachable, err)
+		}
+		return nil
+	case err != nil:
+		return fmt.Errorf("%w: health check failed: %w", ErrNodeUnreachable, err)
+	case res.GetStatus() != healthpb.HealthCheckResponse_SERVING:
+		return fmt.Errorf("%w: node reports status %s", ErrNodeUnreachable, res.GetStatus())
+	}
+	return nil
+}
+
+// PingContract checks that the node is reachable, as Ping does, and that the
+// contract is deployed at ContractAddr. A missing contract is reported as
+// ErrContractNotFound so callers can tell it apart from ErrNodeUnreachable.
+func (cqc *CosmosQueryClient) PingContract(ctx context.Context) error {
+	if err := cqc.Ping(ctx); err != nil {
+		return err
+	}
+
+	queryClient, err := cqc.currentQueryClient()
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrNodeUnreachable, err)
+	}
+
+	if _, hasDeadline := ctx.Deadline(); !hasDeadline {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cqc.config.ConnectionTimeout)
+		defer cancel()
+	}
+
+	_, err = queryClient.ContractInfo(ctx, &wasmtypes.QueryContractInfoRequest{Address: cqc.ContractAddr()})
+	if err != nil {
+		if isNoSuchContractError(err) {
+			return fmt.Errorf("%w: %s: %w", ErrContractNotFound, cqc.ContractAddr(), err)
+		}
+		return fmt.Errorf("%w: contract info query failed: %w", ErrNodeUnreachable, err)
+	}
+	return nil
+}
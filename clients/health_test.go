@@ -0,0 +1,92 @@
+package clients_test
+
+import (
+	"context"
+	"log/slog"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Layer-Edge/light-node/clients"
+)
+
+// panicHandler is a slog.Handler that panics when a record with the message trigger
+// is logged and counts the panics the client reports having recovered
+type panicHandler struct {
+	trigger   string
+	recovered atomic.Int32
+}
+
+func (h *panicHandler) Enabled(context.Context, slog.Level) bool { return true }
+func (h *panicHandler) WithAttrs([]slog.Attr) slog.Handler       { return h }
+func (h *panicHandler) WithGroup(string) slog.Handler            { return h }
+
+func (h *panicHandler) Handle(_ context.Context, r slog.Record) error {
+	switch r.Message {
+	case h.trigger:
+		panic("injected panic")
+	case "Recovered panic in background goroutine":
+		h.recovered.Add(1)
+	}
+	return nil
+}
+
+func TestHealthCheckSurvivesPanics(t *testing.T) {
+	const interval = 10 * time.Millisecond
+
+	// The node is unreachable, so every health check run finds the connection
+	// unhealthy, logs it and panics
+	handler := &panicHandler{trigger: "gRPC connection unhealthy"}
+	config := clients.DefaultClientConfig()
+	config.GrpcURL = "127.0.0.1:1"
+	config.NonBlockingDial = true
+	config.HealthCheckInterval = interval
+	config.UnhealthyThreshold = time.Hour
+	config.Logger = slog.New(handler)
+
+	client, err := clients.NewCosmosQueryClient(clients.WithConfig(config))
+	if err != nil {
+		t.Fatalf("NewCosmosQueryClient: %v", err)
+	}
+
+	started := time.Now()
+	client.StartHealthCheck(context.Background())
+
+	const panics = 5
+	for handler.recovered.Load() < panics {
+		if time.Since(started) > 10*time.Second {
+			t.Fatalf("health check recovered %d panics in 10s, want %d", handler.recovered.Load(), panics)
+		}
+		time.Sleep(interval)
+	}
+
+	// Restarts back off 1, 2, 4 and 8 intervals on top of the interval each run
+	// waits before its first check, 20 intervals in all; restarting straight away
+	// would take 5
+	if elapsed := time.Since(started); elapsed < 15*interval {
+		t.Errorf("%d panics took %v, want restarts to back off for at least %v", panics, elapsed, 15*interval)
+	}
+
+	if client.ActiveEndpoint() != config.GrpcURL {
+		t.Errorf("ActiveEndpoint() = %q after the panics, want %q", client.ActiveEndpoint(), config.GrpcURL)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := client.CloseContext(ctx); err != nil {
+		t.Errorf("CloseContext: %v", err)
+	}
+}
+
+func TestHealthCheckIntervalZeroMeansDefault(t *testing.T) {
+	config := clients.DefaultClientConfig()
+	config.HealthCheckInterval = 0
+	if err := config.Validate(); err != nil {
+		t.Errorf("Validate with no health check interval: %v", err)
+	}
+
+	config.HealthCheckInterval = -time.Second
+	if err := config.Validate(); err == nil {
+		t.Error("Validate accepted a negative health check interval")
+	}
+}
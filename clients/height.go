@@ -0,0 +1,108 @@
+package clients
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/cosmos/cosmos-sdk/client/grpc/cmtservice"
+	grpctypes "github.com/cosmos/cosmos-sdk/types/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// GetMerkleTreeDataAtHeight fetches the tree with the given ID as it existed at a
+// past block height, by setting the x-cosmos-block-height request header. Height 0
+// queries the latest state. The returned height is the one the node echoed back in
+// its response headers, or 0 if it sent none. Historical reads bypass the tree cache.
+func (cqc *CosmosQueryClient) GetMerkleTreeDataAtHeight(ctx context.Context, id string, height int64) (*MerkleTree, int64, error) {
+	if height < 0 {
+		return nil, 0, fmt.Errorf("block height must not be negative, got %d", height)
+	}
+	if height > 0 {
+		ctx = metadata.AppendToOutgoingContext(ctx, grpctypes.GRPCBlockHeightHeader, strconv.FormatInt(height, 10))
+	}
+
+	tree, err := cqc.fetchMerkleTree(ctx, cqc.ContractAddr(), id)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return tree, tree.Height, nil
+}
+
+// LatestBlockHeight returns the height of the newest block the node has. A client
+// on a trusted endpoint can serve as another client's ReferenceHeight:
+//
+//	clients.WithBlockLagCheck(20, trusted.LatestBlockHeight)
+//
+// When ctx has no deadline the call is bounded by QueryTimeout.
+func (cqc *CosmosQueryClient) LatestBlockHeight(ctx context.Context) (int64, error) {
+	done, err := cqc.beginQuery()
+	if err != nil {
+		return 0, err
+	}
+	defer done()
+
+	if err := cqc.ensureConnected(ctx); err != nil {
+		return 0, err
+	}
+	cqc.mu.RLock()
+	conn := cqc.conn
+	cqc.mu.RUnlock()
+	if conn == nil {
+		return 0, ErrNotConnected
+	}
+
+	if _, hasDeadline := ctx.Deadline(); !hasDeadline && cqc.config.QueryTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cqc.config.QueryTimeout)
+		defer cancel()
+	}
+
+	res, err := cmtservice.NewServiceClient(conn).GetLatestBlock(ctx, &cmtservice.GetLatestBlockRequest{})
+	if err != nil {
+		return 0, fmt.Errorf("failed to get latest block: %w", err)
+	}
+	if block := res.GetSdkBlock(); block != nil {
+		return block.Header.Height, nil
+	}
+	return res.GetBlock().GetHeader().Height, nil
+}
+
+// checkBlockLag rejects a tree answered at height when it is more than MaxBlockLag
+// blocks behind ReferenceHeight
+func (cqc *CosmosQueryClient) checkBlockLag(ctx context.Context, id string, height int64) error {
+	if cqc.config.MaxBlockLag <= 0 || cqc.config.ReferenceHeight == nil {
+		return nil
+	}
+	log := cqc.log().With("tree_id", id, "height", height)
+	if height == 0 {
+		log.Warn("Node reported no block height, cannot check it for lag")
+		return nil
+	}
+
+	reference, err := cqc.config.ReferenceHeight(ctx)
+	if err != nil {
+		log.Warn("Failed to get reference block height, accepting tree unchecked", "error", err)
+		return nil
+	}
+	if lag := reference - height; lag > int64(cqc.config.MaxBlockLag) {
+		return fmt.Errorf("%w: tree %s was answered at height %d, %d blocks behind the reference height %d (max %d)",
+			ErrNodeLagging, id, height, lag, reference, cqc.config.MaxBlockLag)
+	}
+	return nil
+}
+
+// blockHeightFromHeader extracts the block height a node reports in its response
+// headers, returning 0 when it is absent or malformed
+func blockHeightFromHeader(header metadata.MD) int64 {
+	values := header.Get(grpctypes.GRPCBlockHeightHeader)
+	if len(values) == 0 {
+		return 0
+	}
+	height, err := strconv.ParseInt(values[0], 10, 64)
+	if err != nil {
+		return 0
+	}
+	return height
+}
@@ -0,0 +1,65 @@
+package clients
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// bearerTokenInterceptor attaches an "authorization: Bearer <token>" header to
+// every call
+func bearerTokenInterceptor(token string) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		ctx = metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+token)
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+// metadataInterceptor merges the metadata fn computes from the call's context
+// into the outgoing metadata of every call
+func metadataInterceptor(fn func(ctx context.Context) metadata.MD) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if extra := fn(ctx); len(extra) > 0 {
+			md, _ := metadata.FromOutgoingContext(ctx)
+			ctx = metadata.NewOutgoingContext(ctx, metadata.Join(md, extra))
+		}
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+// LoggingInterceptor logs every gRPC call with its method, duration and status
+// code. Successful calls are logged at debug level and failures at warn level. A
+// nil logger uses the package logger.
+func LoggingInterceptor(l *slog.Logger) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		start := time.Now()
+		err := invoker(ctx, method, req, reply, cc, opts...)
+
+		log := l
+		if log == nil {
+			log = logger
+		}
+		attrs := []any{"method", method, "duration", time.Since(start), "code", status.Code(err).String()}
+		if err != nil {
+			log.Warn("gRPC call failed", append(attrs, "error", err)...)
+		} else {
+			log.Debug("gRPC call completed", attrs...)
+		}
+		return err
+	}
+}
+
+// LatencyInterceptor reports the duration and outcome of every gRPC call to
+// observe, which can feed a histogram or any other metrics backend
+func LatencyInterceptor(observe func(method string, duration time.Duration, err error)) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		start := time.Now()
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		observe(method, time.Since(start), err)
+		return err
+	}
+}
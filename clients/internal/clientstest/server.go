@@ -0,0 +1,261 @@
+// Package clientstest runs an in-memory wasm query server for the clients package's
+// own tests, so a real CosmosQueryClient can be exercised end to end without a
+// node. Code outside the module should fake the clients.QueryClient interface with
+// the public clients/clientstest package instead.
+package clientstest
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	wasmtypes "github.com/CosmWasm/wasmd/x/wasm/types"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/Layer-Edge/light-node/clients"
+)
+
+// bufconnSize is the in-memory buffer of the listener, large enough for big trees
+const bufconnSize = 1 << 20
+
+// bufconnTarget is the endpoint clients of a FakeQueryServer dial. The address is
+// never connected to; the dialer hands out in-memory connections instead.
+const bufconnTarget = "127.0.0.1:9090"
+
+// SmartQueryHandler answers a smart query in place of the built-in handling.
+// name is the query's single top-level key, such as "get_merkle_tree", and args
+// its value. The returned bytes are sent as the response data; an error should be
+// a gRPC status error, anything else reaches the client as codes.Unknown.
+type SmartQueryHandler func(name string, args json.RawMessage) ([]byte, error)
+
+// FakeQueryServer is an in-memory wasm query server that a real CosmosQueryClient
+// connects to over bufconn, so connection, verification and query logic run end to
+// end without a node:
+//
+//	srv := clientstest.NewFakeQueryServer()
+//	defer srv.Close()
+//	srv.AddTree("tree-1", &clients.MerkleTree{Root: root, Leaves: leaves})
+//	client, err := srv.NewClient()
+//
+// It answers get_merkle_tree and list_merkle_tree_ids from the trees it holds, and
+// ContractInfo for its contract address, DefaultClientConfig's unless set with
+// SetContractAddr. It is safe for concurrent use.
+type FakeQueryServer struct {
+	wasmtypes.UnimplementedQueryServer
+
+	mu           sync.Mutex
+	contractAddr string
+	trees        map[string]*clients.MerkleTree
+	errs         map[string]error
+	listErr      error
+	handler      SmartQueryHandler
+	smartQueries int
+
+	lis *bufconn.Listener
+	srv *grpc.Server
+}
+
+// NewFakeQueryServer starts an empty fake serving on an in-memory listener. Call
+// Close to stop it.
+func NewFakeQueryServer() *FakeQueryServer {
+	s := &FakeQueryServer{
+		contractAddr: clients.DefaultClientConfig().ContractAddr,
+		trees:        make(map[string]*clients.MerkleTree),
+		errs:         make(map[string]error),
+		lis:          bufconn.Listen(bufconnSize),
+		srv:          grpc.NewServer(),
+	}
+	wasmtypes.RegisterQueryServer(s.srv, s)
+	go s.srv.Serve(s.lis)
+	return s
+}
+
+// DialOption routes a client's connections to the fake, whatever endpoint it dials
+func (s *FakeQueryServer) DialOption() grpc.DialOption {
+	return grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+		return s.lis.DialContext(ctx)
+	})
+}
+
+// NewClient returns a CosmosQueryClient connected to the fake, with short timeouts
+// and backoffs and a single connection attempt. opts are applied on top, so a test
+// can still change any setting, including the contract address.
+func (s *FakeQueryServer) NewClient(opts ...clients.Option) (*clients.CosmosQueryClient, error) {
+	base := []clients.Option{
+		clients.WithGrpcURL(bufconnTarget),
+		clients.WithContractAddr(s.ContractAddr()),
+		clients.WithRetries(1, 10*time.Millisecond, 10*time.Millisecond),
+		clients.WithQueryRetries(3, time.Millisecond, 10*time.Millisecond),
+		clients.WithTimeouts(time.Second, time.Second),
+		clients.WithDialOptions(s.DialOption()),
+	}
+	return clients.NewCosmosQueryClient(append(base, opts...)...)
+}
+
+// Close stops the server and drops every client connection
+func (s *FakeQueryServer) Close() {
+	s.srv.Stop()
+	s.lis.Close()
+}
+
+// ContractAddr returns the address of the contract the fake serves
+func (s *FakeQueryServer) ContractAddr() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.contractAddr
+}
+
+// SetContractAddr changes the address of the contract the fake serves. Queries
+// for any other address fail with "no such contract".
+func (s *FakeQueryServer) SetContractAddr(addr string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.contractAddr = addr
+}
+
+// AddTree stores a copy of tree under id, replacing any existing tree
+func (s *FakeQueryServer) AddTree(id string, tree *clients.MerkleTree) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.trees[id] = cloneTree(tree)
+}
+
+// RemoveTree deletes the tree stored under id
+func (s *FakeQueryServer) RemoveTree(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.trees, id)
+}
+
+// SetError makes get_merkle_tree fail with err for id. A nil err clears it.
+func (s *FakeQueryServer) SetError(id string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err == nil {
+		delete(s.errs, id)
+		return
+	}
+	s.errs[id] = err
+}
+
+// SetListError makes list_merkle_tree_ids fail with err. A nil err clears it.
+func (s *FakeQueryServer) SetListError(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.listErr = err
+}
+
+// SetSmartQueryHandler answers every smart query with h instead of the stored
+// trees. A nil h restores the built-in handling.
+func (s *FakeQueryServer) SetSmartQueryHandler(h SmartQueryHandler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handler = h
+}
+
+// SmartQueries returns how many smart queries the fake has received
+func (s *FakeQueryServer) SmartQueries() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.smartQueries
+}
+
+func (s *FakeQueryServer) ContractInfo(ctx context.Context, req *wasmtypes.QueryContractInfoRequest) (*wasmtypes.QueryContractInfoResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if req.Address != s.contractAddr {
+		return nil, noSuchContract(req.Address)
+	}
+	return &wasmtypes.QueryContractInfoResponse{
+		Address: s.contractAddr,
+		ContractInfo: wasmtypes.ContractInfo{
+			CodeID:  1,
+			Creator: s.contractAddr,
+			Label:   "clientstest",
+		},
+	}, nil
+}
+
+func (s *FakeQueryServer) SmartContractState(ctx context.Context, req *wasmtypes.QuerySmartContractStateRequest) (*wasmtypes.QuerySmartContractStateResponse, error) {
+	s.mu.Lock()
+	s.smartQueries++
+	addr, handler := s.contractAddr, s.handler
+	s.mu.Unlock()
+
+	if req.Address != addr {
+		return nil, noSuchContract(req.Address)
+	}
+
+	var query map[string]json.RawMessage
+	if err := json.Unmarshal(req.QueryData, &query); err != nil || len(query) != 1 {
+		return nil, status.Error(codes.InvalidArgument, "query must be a JSON object with a single key")
+	}
+	var name string
+	for name = range query {
+	}
+
+	var data []byte
+	var err error
+	if handler != nil {
+		data, err = handler(name, query[name])
+	} else {
+		data, err = s.answer(name, query[name])
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &wasmtypes.QuerySmartContractStateResponse{Data: data}, nil
+}
+
+// answer serves the contract's queries from the stored trees, failing the way
+// wasmd reports contract errors
+func (s *FakeQueryServer) answer(name string, args json.RawMessage) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch name {
+	case "get_merkle_tree":
+		var req struct {
+			ID string `json:"id"`
+		}
+		if err := json.Unmarshal(args, &req); err != nil {
+			return nil, status.Errorf(codes.Unknown, "Error parsing into type get_merkle_tree: %v: query wasm contract failed", err)
+		}
+		if err, ok := s.errs[req.ID]; ok {
+			return nil, err
+		}
+		tree, ok := s.trees[req.ID]
+		if !ok {
+			return nil, status.Errorf(codes.Unknown, "Merkle tree %s not found: query wasm contract failed", req.ID)
+		}
+		return json.Marshal(tree)
+	case "list_merkle_tree_ids":
+		if s.listErr != nil {
+			return nil, s.listErr
+		}
+		ids := make([]string, 0, len(s.trees))
+		for id := range s.trees {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+		return json.Marshal(ids)
+	default:
+		return nil, status.Errorf(codes.Unknown, "unknown variant `%s`: query wasm contract failed", name)
+	}
+}
+
+func noSuchContract(addr string) error {
+	return status.Errorf(codes.NotFound, "no such contract: %s", addr)
+}
+
+func cloneTree(tree *clients.MerkleTree) *clients.MerkleTree {
+	c := *tree
+	c.Leaves = append([]string(nil), tree.Leaves...)
+	return &c
+}
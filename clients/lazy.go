@@ -0,0 +1,73 @@
+package clients
+
+import "context"
+
+// lazyConnect is a connection attempt started by the first query of a client in
+// LazyConnect mode, shared by every query that arrives while it runs
+type lazyConnect struct {
+	cancel context.CancelFunc // Stops the attempt, called by Close
+	done   chan struct{}
+	err    error
+}
+
+// start connects right away, or defers connecting to the first query when
+// LazyConnect is set. With WarmCacheOnStart an eager client then fills the cache;
+// a failed warm-up is logged by WarmCache and does not fail the start.
+func (cqc *CosmosQueryClient) start(ctx context.Context) error {
+	if cqc.config.LazyConnect {
+		return nil
+	}
+	if err := cqc.connect(ctx); err != nil {
+		return err
+	}
+	if cqc.config.WarmCacheOnStart {
+		cqc.WarmCache(ctx)
+	}
+	return nil
+}
+
+// ensureConnected makes sure a LazyConnect client has a connection, starting the
+// connection attempt if none is running and otherwise waiting for the running
+// one. ctx only bounds the wait; the attempt carries on for later queries and is
+// stopped by Close. A failed attempt is retried by the next query.
+func (cqc *CosmosQueryClient) ensureConnected(ctx context.Context) error {
+	if !cqc.config.LazyConnect {
+		return nil
+	}
+
+	cqc.mu.Lock()
+	if cqc.queryClient != nil || cqc.closed {
+		cqc.mu.Unlock()
+		return nil
+	}
+	attempt := cqc.lazy
+	if attempt == nil {
+		connectCtx, cancel := context.WithCancel(context.Background())
+		attempt = &lazyConnect{cancel: cancel, done: make(chan struct{})}
+		cqc.lazy = attempt
+		cqc.background.Add(1)
+		go func() {
+			defer cqc.background.Done()
+			defer cancel()
+
+			var err error
+			if panicErr := cqc.runRecovered("lazy connect", func() { err = cqc.connect(connectCtx) }); panicErr != nil {
+				err = panicErr
+			}
+
+			cqc.mu.Lock()
+			attempt.err = err
+			cqc.lazy = nil
+			cqc.mu.Unlock()
+			close(attempt.done)
+		}()
+	}
+	cqc.mu.Unlock()
+
+	select {
+	case <-attempt.done:
+		return attempt.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,85 @@
+package clients
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/go-resty/resty/v2"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// lcdSmartQueryResponse is the body of a successful LCD smart query
+type lcdSmartQueryResponse struct {
+	Data json.RawMessage `json:"data"`
+}
+
+// lcdErrorResponse is the body the LCD's gRPC gateway returns for a failed query
+type lcdErrorResponse struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// shouldFallbackToLCD reports whether a failed gRPC query is worth repeating over
+// the LCD: only when the fallback is configured and gRPC itself was the problem
+func (cqc *CosmosQueryClient) shouldFallbackToLCD(err error) bool {
+	if !cqc.config.LCDFallback || cqc.config.LCDURL == "" {
+		return false
+	}
+	return isTransportError(err) || errors.Is(err, ErrCircuitOpen)
+}
+
+// lcd returns the HTTP client used for LCD queries, created on first use
+func (cqc *CosmosQueryClient) lcd() *resty.Client {
+	cqc.lcdOnce.Do(func() {
+		client := resty.New().SetBaseURL(strings.TrimRight(cqc.config.LCDURL, "/"))
+		if cqc.config.QueryTimeout > 0 {
+			client.SetTimeout(cqc.config.QueryTimeout)
+		}
+		if cqc.config.AuthToken != "" {
+			client.SetAuthToken(cqc.config.AuthToken)
+		}
+		cqc.lcdClient = client
+	})
+	return cqc.lcdClient
+}
+
+// lcdSmartQuery runs a smart query against the contract at addr through the LCD's
+// REST endpoint and returns the raw JSON response, the same bytes the gRPC query
+// returns. Errors reported by the node are converted to gRPC status errors so they
+// are classified alike.
+func (cqc *CosmosQueryClient) lcdSmartQuery(ctx context.Context, addr string, queryBytes []byte) ([]byte, error) {
+	req := cqc.lcd().R()
+	if cqc.config.MetadataFunc != nil {
+		req.SetHeaderMultiValues(cqc.config.MetadataFunc(ctx))
+	}
+	resp, err := req.
+		SetContext(ctx).
+		SetPathParams(map[string]string{
+			"addr":  addr,
+			"query": base64.StdEncoding.EncodeToString(queryBytes),
+		}).
+		Get("/cosmwasm/wasm/v1/contract/{addr}/smart/{query}")
+	if err != nil {
+		return nil, fmt.Errorf("LCD request failed: %w", err)
+	}
+
+	if resp.StatusCode() != http.StatusOK {
+		var body lcdErrorResponse
+		if json.Unmarshal(resp.Body(), &body) == nil && body.Message != "" {
+			return nil, status.Error(codes.Code(body.Code), body.Message)
+		}
+		return nil, fmt.Errorf("LCD returned unexpected status code: %d, body: %s", resp.StatusCode(), payloadSnippet(resp.Body()))
+	}
+
+	var body lcdSmartQueryResponse
+	if err := json.Unmarshal(resp.Body(), &body); err != nil {
+		return nil, fmt.Errorf("%w: failed to parse LCD response: %w (payload: %s)", ErrInvalidResponse, err, payloadSnippet(resp.Body()))
+	}
+	return body.Data, nil
+}
@@ -0,0 +1,24 @@
+package clients
+
+import "log/slog"
+
+// logger receives all log events emitted by the clients package
+var logger = slog.Default()
+
+// SetLogger replaces the logger used by the clients package. Passing nil restores
+// slog.Default().
+func SetLogger(l *slog.Logger) {
+	if l == nil {
+		l = slog.Default()
+	}
+	logger = l
+}
+
+// log returns the logger configured for this client, falling back to the package
+// logger
+func (cqc *CosmosQueryClient) log() *slog.Logger {
+	if cqc.config.Logger != nil {
+		return cqc.config.Logger
+	}
+	return logger
+}
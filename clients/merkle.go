@@ -0,0 +1,361 @@
+package clients
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// Merkle hashing convention. With DefaultMerkleConfig it matches the risc0 merkle
+// service (risc0-merkle-service/methods/guest):
+//
+//   - A leaf node is H(LeafPrefix || leaf value's UTF-8 bytes), written as
+//     lowercase hex.
+//   - A parent node is H(NodePrefix || left child's hex string || right child's hex
+//     string), again written as lowercase hex. The hex text is hashed, not the
+//     decoded digest bytes.
+//   - When a level has an odd number of nodes, the last node is promoted to the
+//     next level unchanged.
+//
+// H is SHA-256 and both prefixes are empty by default. With SortPairs the two
+// children are ordered so the smaller hash comes first, whichever side it is on.
+// A proof lists the sibling of
+// every level on the path from the leaf to the root, bottom-up. Levels where the
+// node was promoted have no sibling and contribute no entry.
+//
+// With OpenZeppelin set the tree is built as OpenZeppelin's merkle-tree library
+// builds it instead:
+//
+//   - Each leaf is a hex digest and is its own leaf hash, as in SimpleMerkleTree.
+//     StandardMerkleTree leaves are keccak256(keccak256(abi.encode(values))),
+//     computed by the caller.
+//   - The leaf hashes are sorted, and a parent node is H(smaller child's digest
+//     bytes || larger child's digest bytes).
+//   - The nodes form a complete binary tree stored in an array, root first, with
+//     the sorted leaves in reverse order at the end. Node i has the children 2i+1
+//     and 2i+2. An odd level therefore pairs its last node with the first node of
+//     the level below rather than promoting it.
+
+// Hasher is the hash function used to build merkle trees
+type Hasher interface {
+	Hash(data []byte) []byte
+}
+
+// SHA256Hasher hashes with SHA-256, as the production contract does
+type SHA256Hasher struct{}
+
+func (SHA256Hasher) Hash(data []byte) []byte {
+	sum := sha256.Sum256(data)
+	return sum[:]
+}
+
+// Keccak256Hasher hashes with Keccak-256, as EVM contracts do
+type Keccak256Hasher struct{}
+
+func (Keccak256Hasher) Hash(data []byte) []byte {
+	return crypto.Keccak256(data)
+}
+
+// HasherByName returns the hasher for "sha256" or "keccak256"
+func HasherByName(name string) (Hasher, error) {
+	switch strings.ToLower(name) {
+	case "sha256", "sha-256":
+		return SHA256Hasher{}, nil
+	case "keccak256", "keccak-256":
+		return Keccak256Hasher{}, nil
+	default:
+		return nil, fmt.Errorf("unknown merkle hash %q, expected sha256 or keccak256", name)
+	}
+}
+
+// hasherName returns the name HasherByName accepts for h, or false for a hasher it
+// does not know
+func hasherName(h Hasher) (string, bool) {
+	switch h.(type) {
+	case SHA256Hasher, *SHA256Hasher:
+		return "sha256", true
+	case Keccak256Hasher, *Keccak256Hasher:
+		return "keccak256", true
+	default:
+		return "", false
+	}
+}
+
+// MerkleConfig selects the hash function and domain separation used for merkle
+// roots and proofs. The zero value is equivalent to DefaultMerkleConfig.
+type MerkleConfig struct {
+	Hasher     Hasher // Nil means SHA-256
+	LeafPrefix []byte // Prepended to each leaf before hashing, e.g. 0x00
+	NodePrefix []byte // Prepended to each pair of children before hashing, e.g. 0x01
+	// Order each pair of children by value before hashing, as OpenZeppelin's
+	// MerkleProof does, so proofs verify without knowing which side each sibling
+	// is on. ProofNode.Right is still filled in but ignored when verifying.
+	// OpenZeppelin also hashes the digest bytes rather than their hex and lays out
+	// odd levels differently, so this alone does not make roots match its trees;
+	// set OpenZeppelin for that.
+	SortPairs bool
+	// Build trees as OpenZeppelin's merkle-tree library does, see above. Leaves
+	// must be digests, the prefixes are ignored and pairs are always sorted. Set
+	// Hasher to Keccak256Hasher to match its roots. Multiproofs use OpenZeppelin's
+	// layout too, see MultiProof.
+	OpenZeppelin bool
+}
+
+// DefaultMerkleConfig returns the configuration matching the production contract
+func DefaultMerkleConfig() MerkleConfig {
+	return MerkleConfig{Hasher: SHA256Hasher{}}
+}
+
+// ProofNode is one step of a merkle inclusion proof
+type ProofNode struct {
+	Hash  string `json:"hash"`  // Sibling hash, lowercase hex
+	Right bool   `json:"right"` // True when the sibling sits to the right of the running hash
+}
+
+// GenerateProof returns the inclusion proof for leaf under DefaultMerkleConfig
+func (t *MerkleTree) GenerateProof(leaf string) ([]ProofNode, error) {
+	return DefaultMerkleConfig().GenerateProof(t.Leaves, leaf)
+}
+
+// ValidateTree recomputes the root from the tree's leaves under DefaultMerkleConfig
+// and checks it against Root
+func (t *MerkleTree) ValidateTree() error {
+	return DefaultMerkleConfig().ValidateTree(t)
+}
+
+// ComputeRoot returns the merkle root of leaves under DefaultMerkleConfig
+func ComputeRoot(leaves []string) (string, error) {
+	return DefaultMerkleConfig().ComputeRoot(leaves)
+}
+
+// VerifyProof checks an inclusion proof under DefaultMerkleConfig
+func VerifyProof(root, leaf string, proof []ProofNode) (bool, error) {
+	return DefaultMerkleConfig().VerifyProof(root, leaf, proof)
+}
+
+// GenerateProof returns the inclusion proof for leaf, the ordered sibling hashes
+// needed to recompute the root of leaves from the leaf. If the leaf occurs more
+// than once the proof is for its first occurrence.
+func (mc MerkleConfig) GenerateProof(leaves []string, leaf string) ([]ProofNode, error) {
+	if mc.OpenZeppelin {
+		return mc.ozProof(leaves, leaf)
+	}
+
+	index := -1
+	for i, l := range leaves {
+		if l == leaf {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		return nil, fmt.Errorf("%w: %q", ErrLeafNotFound, leaf)
+	}
+	levels := mc.buildLevels(leaves)
+	proof := []ProofNode{}
+	for _, level := range levels[:len(levels)-1] {
+		sibling := index ^ 1
+		if sibling < len(level) {
+			proof = append(proof, ProofNode{Hash: level[sibling], Right: index%2 == 0})
+		}
+		index /= 2
+	}
+
+	return proof, nil
+}
+
+// VerifyProof recomputes the root from leaf and its inclusion proof and reports
+// whether it matches root. The root and sibling hashes may be 0x-prefixed or
+// uppercase, see NormalizeHex. Malformed input, a root or sibling that is not hex
+// or not a digest-sized hash, is reported as an error wrapping ErrInvalidProof
+// rather than as a plain mismatch.
+func (mc MerkleConfig) VerifyProof(root, leaf string, proof []ProofNode) (bool, error) {
+	root = NormalizeHex(root)
+	if err := mc.checkDigest("root", root); err != nil {
+		return false, err
+	}
+
+	if mc.OpenZeppelin {
+		if err := mc.checkDigest("leaf", NormalizeHex(leaf)); err != nil {
+			return false, err
+		}
+	}
+
+	current := mc.hashLeaf(leaf)
+	for i, node := range proof {
+		sibling := NormalizeHex(node.Hash)
+		if err := mc.checkDigest(fmt.Sprintf("proof node %d", i), sibling); err != nil {
+			return false, err
+		}
+		if node.Right {
+			current = mc.hashNode(current, sibling)
+		} else {
+			current = mc.hashNode(sibling, current)
+		}
+	}
+
+	return current == root, nil
+}
+
+// ComputeRoot returns the merkle root of leaves in normalized form, lowercase hex
+// without a 0x prefix. The leaves are hashed exactly as given.
+func (mc MerkleConfig) ComputeRoot(leaves []string) (string, error) {
+	if len(leaves) == 0 {
+		return "", fmt.Errorf("%w: cannot compute the root of a tree without leaves", ErrInvalidProof)
+	}
+	if mc.OpenZeppelin {
+		tree, err := mc.ozTree(leaves)
+		if err != nil {
+			return "", err
+		}
+		return tree[0], nil
+	}
+	levels := mc.buildLevels(leaves)
+	return levels[len(levels)-1][0], nil
+}
+
+// ValidateTree recomputes the root from the tree's leaves and checks it against
+// Root, catching leaves that were corrupted or do not belong to the claimed root.
+// Root may be 0x-prefixed or uppercase.
+func (mc MerkleConfig) ValidateTree(t *MerkleTree) error {
+	root, err := mc.ComputeRoot(t.Leaves)
+	if err != nil {
+		return err
+	}
+	if root != NormalizeHex(t.Root) {
+		return fmt.Errorf("%w: leaves hash to %s, tree claims %s", ErrRootMismatch, root, t.Root)
+	}
+	return nil
+}
+
+// checkDigest verifies that value is a hex-encoded digest of the hash size
+func (mc MerkleConfig) checkDigest(name, value string) error {
+	decoded, err := hex.DecodeString(value)
+	if err != nil {
+		return fmt.Errorf("%w: %s is not valid hex: %v", ErrInvalidProof, name, err)
+	}
+	if size := len(mc.hasher().Hash(nil)); len(decoded) != size {
+		return fmt.Errorf("%w: %s is %d bytes, expected %d", ErrInvalidProof, name, len(decoded), size)
+	}
+	return nil
+}
+
+// buildLevels hashes leaves and every level above them, returning the levels from
+// the leaf hashes up to the single root hash. It expects at least one leaf.
+func (mc MerkleConfig) buildLevels(leaves []string) [][]string {
+	level := make([]string, len(leaves))
+	for i, leaf := range leaves {
+		level[i] = mc.hashLeaf(leaf)
+	}
+
+	levels := [][]string{level}
+	for len(level) > 1 {
+		next := make([]string, 0, (len(level)+1)/2)
+		for i := 0; i < len(level); i += 2 {
+			if i+1 < len(level) {
+				next = append(next, mc.hashNode(level[i], level[i+1]))
+			} else {
+				next = append(next, level[i])
+			}
+		}
+		levels = append(levels, next)
+		level = next
+	}
+	return levels
+}
+
+// ozTree lays the leaves out as OpenZeppelin's merkle-tree library does, returning
+// the array of nodes with the root first. It expects at least one leaf.
+func (mc MerkleConfig) ozTree(leaves []string) ([]string, error) {
+	if len(leaves) == 0 {
+		return nil, fmt.Errorf("%w: cannot build a tree without leaves", ErrInvalidProof)
+	}
+	hashes := make([]string, len(leaves))
+	for i, leaf := range leaves {
+		hashes[i] = NormalizeHex(leaf)
+		decoded, err := hex.DecodeString(hashes[i])
+		if err != nil {
+			return nil, fmt.Errorf("%w: leaf %d (%q) is not a hex digest: %v", ErrInvalidLeaf, i, leaf, err)
+		}
+		if size := len(mc.hasher().Hash(nil)); len(decoded) != size {
+			return nil, fmt.Errorf("%w: leaf %d is %d bytes, expected a %d-byte digest", ErrInvalidLeaf, i, len(decoded), size)
+		}
+	}
+	sort.Strings(hashes)
+
+	tree := make([]string, 2*len(hashes)-1)
+	for i, hash := range hashes {
+		tree[len(tree)-1-i] = hash
+	}
+	for i := len(tree) - 1 - len(hashes); i >= 0; i-- {
+		tree[i] = mc.hashNode(tree[2*i+1], tree[2*i+2])
+	}
+	return tree, nil
+}
+
+// ozProof returns the inclusion proof for leaf in the OpenZeppelin layout. A leaf
+// that occurs more than once is proven at its first position in the array's leaf
+// section.
+func (mc MerkleConfig) ozProof(leaves []string, leaf string) ([]ProofNode, error) {
+	tree, err := mc.ozTree(leaves)
+	if err != nil {
+		return nil, err
+	}
+
+	target := NormalizeHex(leaf)
+	index := -1
+	for i := len(tree) - 1; i >= len(tree)-len(leaves); i-- {
+		if tree[i] == target {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		return nil, fmt.Errorf("%w: %q", ErrLeafNotFound, leaf)
+	}
+
+	proof := []ProofNode{}
+	for ; index > 0; index = (index - 1) / 2 {
+		// Odd positions are left children
+		if index%2 == 1 {
+			proof = append(proof, ProofNode{Hash: tree[index+1], Right: true})
+		} else {
+			proof = append(proof, ProofNode{Hash: tree[index-1], Right: false})
+		}
+	}
+	return proof, nil
+}
+
+func (mc MerkleConfig) hasher() Hasher {
+	if mc.Hasher == nil {
+		return SHA256Hasher{}
+	}
+	return mc.Hasher
+}
+
+func (mc MerkleConfig) hashLeaf(leaf string) string {
+	if mc.OpenZeppelin {
+		return NormalizeHex(leaf)
+	}
+	data := append(append([]byte{}, mc.LeafPrefix...), leaf...)
+	return hex.EncodeToString(mc.hasher().Hash(data))
+}
+
+func (mc MerkleConfig) hashNode(left, right string) string {
+	// Lowercase hex of equal-length digests sorts like the digest bytes
+	if (mc.SortPairs || mc.OpenZeppelin) && right < left {
+		left, right = right, left
+	}
+	if mc.OpenZeppelin {
+		// Both are digests checked by the caller
+		l, _ := hex.DecodeString(left)
+		r, _ := hex.DecodeString(right)
+		return hex.EncodeToString(mc.hasher().Hash(append(l, r...)))
+	}
+	data := append(append([]byte{}, mc.NodePrefix...), left+right...)
+	return hex.EncodeToString(mc.hasher().Hash(data))
+}
@@ -0,0 +1,230 @@
+package clients_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"math/big"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/Layer-Edge/light-node/clients"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// risc0Tree was built by the risc0 merkle service's guest code
+// (risc0-merkle-service/methods/guest), inserting the leaves in order
+var risc0Tree = clients.MerkleTree{
+	Root:   "65859a46039b52815c5fb014d30a6d190ac27ed8920acb46086de8bcae3229a0",
+	Leaves: []string{"apple", "banana", "cherry", "date", "elderberry"},
+}
+
+// risc0CherryProof is the guest's proof for "cherry", the third leaf: its right
+// sibling "date", the left pair "apple"/"banana", then the promoted "elderberry"
+var risc0CherryProof = []clients.ProofNode{
+	{Hash: "0e87632cd46bd4907c516317eb6d81fe0f921a23c7643018f21292894b470681", Right: true},
+	{Hash: "004e48bbd922653f4cb0b656f13dbaaf72974acea5d6d836ba240ddcc780a994", Right: false},
+	{Hash: "f1915a182a1e82257c1cb2e4c5b20676eada7671b9200a38adfc8c8ea776a2bd", Right: true},
+}
+
+func TestGenerateProofMatchesRisc0(t *testing.T) {
+	proof, err := risc0Tree.GenerateProof("cherry")
+	if err != nil {
+		t.Fatalf("GenerateProof: %v", err)
+	}
+	if len(proof) != len(risc0CherryProof) {
+		t.Fatalf("GenerateProof = %+v, want %+v", proof, risc0CherryProof)
+	}
+	for i := range proof {
+		if proof[i] != risc0CherryProof[i] {
+			t.Errorf("proof node %d = %+v, want %+v", i, proof[i], risc0CherryProof[i])
+		}
+	}
+
+	if _, err := risc0Tree.GenerateProof("fig"); !errors.Is(err, clients.ErrLeafNotFound) {
+		t.Errorf("GenerateProof(fig) error = %v, want ErrLeafNotFound", err)
+	}
+}
+
+func TestVerifyProof(t *testing.T) {
+	flipped := append([]clients.ProofNode(nil), risc0CherryProof...)
+	flipped[0].Right = false
+	withSibling := func(hash string) []clients.ProofNode {
+		proof := append([]clients.ProofNode(nil), risc0CherryProof...)
+		proof[1].Hash = hash
+		return proof
+	}
+
+	tests := []struct {
+		name    string
+		root    string
+		leaf    string
+		proof   []clients.ProofNode
+		want    bool
+		wantErr error
+	}{
+		{name: "risc0 proof", root: risc0Tree.Root, leaf: "cherry", proof: risc0CherryProof, want: true},
+		{name: "prefixed uppercase root", root: "0x" + strings.ToUpper(risc0Tree.Root), leaf: "cherry", proof: risc0CherryProof, want: true},
+		{name: "prefixed uppercase sibling", root: risc0Tree.Root, leaf: "cherry", proof: withSibling("0X004E48BBD922653F4CB0B656F13DBAAF72974ACEA5D6D836BA240DDCC780A994"), want: true},
+		{name: "other leaf", root: risc0Tree.Root, leaf: "date", proof: risc0CherryProof, want: false},
+		{name: "sibling on the wrong side", root: risc0Tree.Root, leaf: "cherry", proof: flipped, want: false},
+		{name: "missing step", root: risc0Tree.Root, leaf: "cherry", proof: risc0CherryProof[:2], want: false},
+		{name: "root not hex", root: "zz" + risc0Tree.Root[2:], leaf: "cherry", proof: risc0CherryProof, wantErr: clients.ErrInvalidProof},
+		{name: "root too short", root: risc0Tree.Root[:62], leaf: "cherry", proof: risc0CherryProof, wantErr: clients.ErrInvalidProof},
+		{name: "root too long", root: risc0Tree.Root + "00", leaf: "cherry", proof: risc0CherryProof, wantErr: clients.ErrInvalidProof},
+		{name: "sibling not hex", root: risc0Tree.Root, leaf: "cherry", proof: withSibling("not a hash"), wantErr: clients.ErrInvalidProof},
+		{name: "sibling odd length", root: risc0Tree.Root, leaf: "cherry", proof: withSibling("abc"), wantErr: clients.ErrInvalidProof},
+		{name: "sibling wrong length", root: risc0Tree.Root, leaf: "cherry", proof: withSibling("abcd"), wantErr: clients.ErrInvalidProof},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := clients.VerifyProof(tt.root, tt.leaf, tt.proof)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("VerifyProof error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("VerifyProof: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("VerifyProof = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateTree(t *testing.T) {
+	tests := []struct {
+		name    string
+		tree    clients.MerkleTree
+		wantErr error
+	}{
+		{name: "risc0 tree", tree: risc0Tree},
+		{name: "prefixed uppercase root", tree: clients.MerkleTree{Root: "0x" + strings.ToUpper(risc0Tree.Root), Leaves: risc0Tree.Leaves}},
+		{name: "single leaf is its own root", tree: clients.MerkleTree{
+			Root:   "3a7bd3e2360a3d29eea436fcfb7e44c735d117c42d1c1835420b6b9942dd4f1b",
+			Leaves: []string{"apple"},
+		}},
+		{name: "corrupted leaf", tree: clients.MerkleTree{Root: risc0Tree.Root, Leaves: []string{"apple", "banana", "cherry", "date", "fig"}}, wantErr: clients.ErrRootMismatch},
+		{name: "reordered leaves", tree: clients.MerkleTree{Root: risc0Tree.Root, Leaves: []string{"banana", "apple", "cherry", "date", "elderberry"}}, wantErr: clients.ErrRootMismatch},
+		{name: "no leaves", tree: clients.MerkleTree{Root: risc0Tree.Root}, wantErr: clients.ErrInvalidProof},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.tree.ValidateTree(); !errors.Is(err, tt.wantErr) {
+				t.Errorf("ValidateTree error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// ozConfig builds trees the way OpenZeppelin's merkle-tree library does
+var ozConfig = clients.MerkleConfig{Hasher: clients.Keccak256Hasher{}, OpenZeppelin: true}
+
+// ozLeaf returns the StandardMerkleTree leaf hash of an ["address", "uint256"] value
+func ozLeaf(t *testing.T, addr, amount string) string {
+	t.Helper()
+	value, ok := new(big.Int).SetString(amount, 10)
+	if !ok {
+		t.Fatalf("bad amount %q", amount)
+	}
+	encoded := append(common.LeftPadBytes(common.HexToAddress(addr).Bytes(), 32), common.LeftPadBytes(value.Bytes(), 32)...)
+	return hex.EncodeToString(crypto.Keccak256(crypto.Keccak256(encoded)))
+}
+
+// ozPair hashes two digests as OpenZeppelin's commutative keccak256 does
+func ozPair(a, b string) string {
+	left, _ := hex.DecodeString(a)
+	right, _ := hex.DecodeString(b)
+	if bytes.Compare(right, left) < 0 {
+		left, right = right, left
+	}
+	return hex.EncodeToString(crypto.Keccak256(left, right))
+}
+
+func TestOpenZeppelinStandardTree(t *testing.T) {
+	// The example from the @openzeppelin/merkle-tree README:
+	//
+	//	StandardMerkleTree.of([
+	//	  ["0x1111111111111111111111111111111111111111", "5000000000000000000"],
+	//	  ["0x2222222222222222222222222222222222222222", "2500000000000000000"],
+	//	], ["address", "uint256"])
+	const root = "0xd4dee0beab2d53f2cc83e567171bd2820e49898130a22622b10ead383e90bd77"
+	leaves := []string{
+		ozLeaf(t, "0x1111111111111111111111111111111111111111", "5000000000000000000"),
+		ozLeaf(t, "0x2222222222222222222222222222222222222222", "2500000000000000000"),
+	}
+
+	got, err := ozConfig.ComputeRoot(leaves)
+	if err != nil {
+		t.Fatalf("ComputeRoot: %v", err)
+	}
+	if got != clients.NormalizeHex(root) {
+		t.Errorf("ComputeRoot = %s, want OpenZeppelin's %s", got, root)
+	}
+
+	proof, err := ozConfig.GenerateProof(leaves, leaves[0])
+	if err != nil {
+		t.Fatalf("GenerateProof: %v", err)
+	}
+	if len(proof) != 1 || proof[0].Hash != leaves[1] {
+		t.Errorf("GenerateProof = %+v, want the other leaf as the only sibling", proof)
+	}
+	if ok, err := ozConfig.VerifyProof(root, "0x"+leaves[0], proof); err != nil || !ok {
+		t.Errorf("VerifyProof = %v, %v, want true", ok, err)
+	}
+
+	// Hashing the hex of the digests, even with sorted pairs, gives another root
+	hexConfig := clients.MerkleConfig{Hasher: clients.Keccak256Hasher{}, SortPairs: true}
+	if other, _ := hexConfig.ComputeRoot(leaves); other == got {
+		t.Errorf("SortPairs alone reproduced the OpenZeppelin root %s", other)
+	}
+}
+
+func TestOpenZeppelinOddLevels(t *testing.T) {
+	leaves := make([]string, 5)
+	for i, value := range []string{"a", "b", "c", "d", "e"} {
+		leaves[i] = hex.EncodeToString(crypto.Keccak256([]byte(value)))
+	}
+	sorted := append([]string(nil), leaves...)
+	sort.Strings(sorted)
+
+	// Nine nodes with the sorted leaves reversed in positions 4 to 8: the fifth
+	// leaf pairs with the first two leaves' parent instead of being promoted
+	want := ozPair(ozPair(ozPair(sorted[1], sorted[0]), sorted[4]), ozPair(sorted[3], sorted[2]))
+
+	root, err := ozConfig.ComputeRoot(leaves)
+	if err != nil {
+		t.Fatalf("ComputeRoot: %v", err)
+	}
+	if root != want {
+		t.Errorf("ComputeRoot = %s, want %s", root, want)
+	}
+
+	for _, leaf := range leaves {
+		proof, err := ozConfig.GenerateProof(leaves, leaf)
+		if err != nil {
+			t.Fatalf("GenerateProof(%s): %v", leaf, err)
+		}
+		if ok, err := ozConfig.VerifyProof(root, leaf, proof); err != nil || !ok {
+			t.Errorf("VerifyProof(%s) = %v, %v, want true", leaf, ok, err)
+		}
+	}
+}
+
+func TestOpenZeppelinRejectsNonDigestLeaves(t *testing.T) {
+	for _, leaf := range []string{"not hex", "abcd"} {
+		if _, err := ozConfig.ComputeRoot([]string{leaf}); !errors.Is(err, clients.ErrInvalidLeaf) {
+			t.Errorf("ComputeRoot(%q) error = %v, want ErrInvalidLeaf", leaf, err)
+		}
+	}
+	if _, err := ozConfig.GenerateMultiProof([]string{"ab"}, []string{"ab"}); !errors.Is(err, clients.ErrInvalidLeaf) {
+		t.Errorf("GenerateMultiProof error = %v, want ErrInvalidLeaf", err)
+	}
+}
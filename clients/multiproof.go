@@ -0,0 +1,302 @@
+package clients
+
+import (
+	"fmt"
+	"sort"
+)
+
+// MultiProof proves several leaves of one tree against its root, sharing the
+// hashes their paths have in common.
+//
+// The verifier rebuilds the tree level by level, bottom-up, knowing only the
+// nodes on the proven leaves' paths. Within a level the known nodes are visited
+// in increasing position, and each known node that has a sibling is one step:
+//
+//   - Flag true: the sibling is known too, and the pair is hashed once for both.
+//   - Flag false: the sibling is the next entry of Hashes.
+//
+// A known node without a sibling, the last node of an odd level, is promoted and
+// takes no step. Flags therefore holds one entry per step and Hashes one entry per
+// false flag, both in the order the steps are taken. Pairs are hashed left to
+// right as in any other proof, or sorted when the config sets SortPairs. This
+// layout is specific to this package.
+//
+// Trees in the OpenZeppelin layout get OpenZeppelin's multiproofs instead, as
+// built by its merkle-tree library's getMultiProof and checked by MerkleProof's
+// multiProofVerify. The verifier keeps a queue of known nodes, starting with the
+// proven leaves sorted in ascending order, their order in the tree. Each flag takes
+// the first node off the queue and pairs it with the next node of the queue when
+// true, or with the next entry of Hashes when false, appending the parent to the
+// queue; the last node left is the root. Hashes and Flags can be passed to
+// multiProofVerify as proof and proofFlags, with the proven leaves sorted.
+// Indices are still the leaves' positions in the tree's leaves as given.
+type MultiProof struct {
+	LeafCount int      `json:"leaf_count"` // Number of leaves in the tree
+	Indices   []int    `json:"indices"`    // Position of each proven leaf, in the order the leaves are given
+	Hashes    []string `json:"hashes"`     // Sibling hashes, lowercase hex
+	Flags     []bool   `json:"flags"`      // One per step, true when the sibling is itself known
+}
+
+// GenerateMultiProof returns a proof for several of the tree's leaves under
+// DefaultMerkleConfig
+func (t *MerkleTree) GenerateMultiProof(leaves []string) (MultiProof, error) {
+	return DefaultMerkleConfig().GenerateMultiProof(t.Leaves, leaves)
+}
+
+// VerifyMultiProof checks a multiproof under DefaultMerkleConfig
+func VerifyMultiProof(root string, leaves []string, proof MultiProof) (bool, error) {
+	return DefaultMerkleConfig().VerifyMultiProof(root, leaves, proof)
+}
+
+// GenerateMultiProof returns a proof that every entry of proven is a leaf of
+// leaves. As with GenerateProof, a leaf that occurs more than once is proven at
+// its first occurrence. Proving the same leaf twice is an error.
+func (mc MerkleConfig) GenerateMultiProof(leaves, proven []string) (MultiProof, error) {
+	if len(proven) == 0 {
+		return MultiProof{}, fmt.Errorf("%w: no leaves to prove", ErrInvalidProof)
+	}
+	if mc.OpenZeppelin {
+		return mc.ozMultiProof(leaves, proven)
+	}
+
+	first := make(map[string]int, len(leaves))
+	for i, leaf := range leaves {
+		if _, ok := first[leaf]; !ok {
+			first[leaf] = i
+		}
+	}
+
+	proof := MultiProof{LeafCount: len(leaves), Indices: make([]int, len(proven)), Hashes: []string{}, Flags: []bool{}}
+	known := make(map[int]bool, len(proven))
+	for i, leaf := range proven {
+		index, ok := first[leaf]
+		if !ok {
+			return MultiProof{}, fmt.Errorf("%w: %q", ErrLeafNotFound, leaf)
+		}
+		if known[index] {
+			return MultiProof{}, fmt.Errorf("%w: leaf %q is given more than once", ErrInvalidProof, leaf)
+		}
+		known[index] = true
+		proof.Indices[i] = index
+	}
+
+	levels := mc.buildLevels(leaves)
+	for _, level := range levels[:len(levels)-1] {
+		next := make(map[int]bool, len(known))
+		for _, pos := range sortedPositions(known) {
+			sibling := pos ^ 1
+			switch {
+			case sibling >= len(level):
+				// Promoted, no step
+			case known[sibling]:
+				// The pair is one step, taken when its left node is visited
+				if pos%2 == 0 {
+					proof.Flags = append(proof.Flags, true)
+				}
+			default:
+				proof.Flags = append(proof.Flags, false)
+				proof.Hashes = append(proof.Hashes, level[sibling])
+			}
+			next[pos/2] = true
+		}
+		known = next
+	}
+
+	return proof, nil
+}
+
+// VerifyMultiProof recomputes the root from leaves and the proof and reports
+// whether it matches root. leaves must be given in the same order as when the
+// proof was generated. The root and hashes may be 0x-prefixed or uppercase, see
+// NormalizeHex. A proof whose shape does not fit its indices, or whose
+// hashes are not digests, is reported as an error wrapping ErrInvalidProof.
+func (mc MerkleConfig) VerifyMultiProof(root string, leaves []string, proof MultiProof) (bool, error) {
+	root = NormalizeHex(root)
+	if err := mc.checkDigest("root", root); err != nil {
+		return false, err
+	}
+	if len(leaves) == 0 || len(leaves) != len(proof.Indices) {
+		return false, fmt.Errorf("%w: %d leaves for %d indices", ErrInvalidProof, len(leaves), len(proof.Indices))
+	}
+	hashes := make([]string, len(proof.Hashes))
+	for i, hash := range proof.Hashes {
+		hashes[i] = NormalizeHex(hash)
+		if err := mc.checkDigest(fmt.Sprintf("proof hash %d", i), hashes[i]); err != nil {
+			return false, err
+		}
+	}
+
+	known := make(map[int]string, len(leaves))
+	for i, index := range proof.Indices {
+		if index < 0 || index >= proof.LeafCount {
+			return false, fmt.Errorf("%w: leaf index %d is outside a tree of %d leaves", ErrInvalidProof, index, proof.LeafCount)
+		}
+		if _, ok := known[index]; ok {
+			return false, fmt.Errorf("%w: leaf index %d is given more than once", ErrInvalidProof, index)
+		}
+		known[index] = mc.hashLeaf(leaves[i])
+	}
+	if mc.OpenZeppelin {
+		return mc.ozVerifyMultiProof(root, leaves, hashes, proof.Flags)
+	}
+
+	flags := proof.Flags
+	for width := proof.LeafCount; width > 1; width = (width + 1) / 2 {
+		next := make(map[int]string, len(known))
+		positions := make(map[int]bool, len(known))
+		for pos := range known {
+			positions[pos] = true
+		}
+		for _, pos := range sortedPositions(positions) {
+			hash, sibling := known[pos], pos^1
+			siblingHash, siblingKnown := known[sibling]
+			switch {
+			case sibling >= width:
+				next[pos/2] = hash
+				continue
+			case siblingKnown:
+				if pos%2 == 1 {
+					// Already hashed with its left sibling
+					continue
+				}
+				if len(flags) == 0 || !flags[0] {
+					return false, fmt.Errorf("%w: flags do not match the proven leaves", ErrInvalidProof)
+				}
+				flags = flags[1:]
+			default:
+				if len(flags) == 0 || flags[0] || len(hashes) == 0 {
+					return false, fmt.Errorf("%w: flags and hashes do not match the proven leaves", ErrInvalidProof)
+				}
+				flags, siblingHash, hashes = flags[1:], hashes[0], hashes[1:]
+			}
+
+			if pos%2 == 0 {
+				next[pos/2] = mc.hashNode(hash, siblingHash)
+			} else {
+				next[pos/2] = mc.hashNode(siblingHash, hash)
+			}
+		}
+		known = next
+	}
+	if len(flags) != 0 || len(hashes) != 0 {
+		return false, fmt.Errorf("%w: %d flags and %d hashes left over", ErrInvalidProof, len(flags), len(hashes))
+	}
+
+	return known[0] == root, nil
+}
+
+// ozMultiProof returns the multiproof for proven in the OpenZeppelin layout,
+// walking the node array as OpenZeppelin's getMultiProof does
+func (mc MerkleConfig) ozMultiProof(leaves, proven []string) (MultiProof, error) {
+	tree, err := mc.ozTree(leaves)
+	if err != nil {
+		return MultiProof{}, err
+	}
+
+	// A leaf that occurs more than once is proven at its first position, in the
+	// leaves as given and in the node array as for ozProof
+	first := make(map[string]int, len(leaves))
+	for i, leaf := range leaves {
+		if _, ok := first[NormalizeHex(leaf)]; !ok {
+			first[NormalizeHex(leaf)] = i
+		}
+	}
+	node := make(map[string]int, len(leaves))
+	for i := len(tree) - 1; i >= len(tree)-len(leaves); i-- {
+		if _, ok := node[tree[i]]; !ok {
+			node[tree[i]] = i
+		}
+	}
+
+	proof := MultiProof{LeafCount: len(leaves), Indices: make([]int, len(proven)), Hashes: []string{}, Flags: []bool{}}
+	queue := make([]int, len(proven))
+	seen := make(map[string]bool, len(proven))
+	for i, leaf := range proven {
+		hash := NormalizeHex(leaf)
+		index, ok := first[hash]
+		if !ok {
+			return MultiProof{}, fmt.Errorf("%w: %q", ErrLeafNotFound, leaf)
+		}
+		if seen[hash] {
+			return MultiProof{}, fmt.Errorf("%w: leaf %q is given more than once", ErrInvalidProof, leaf)
+		}
+		seen[hash] = true
+		proof.Indices[i] = index
+		queue[i] = node[hash]
+	}
+
+	// Deepest nodes first; parents are appended in the order they are formed
+	sort.Sort(sort.Reverse(sort.IntSlice(queue)))
+	for len(queue) > 0 && queue[0] > 0 {
+		index := queue[0]
+		queue = queue[1:]
+		// Odd positions are left children
+		sibling := index - 1
+		if index%2 == 1 {
+			sibling = index + 1
+		}
+		if len(queue) > 0 && queue[0] == sibling {
+			proof.Flags = append(proof.Flags, true)
+			queue = queue[1:]
+		} else {
+			proof.Flags = append(proof.Flags, false)
+			proof.Hashes = append(proof.Hashes, tree[sibling])
+		}
+		queue = append(queue, (index-1)/2)
+	}
+
+	return proof, nil
+}
+
+// ozVerifyMultiProof recomputes the root of an OpenZeppelin multiproof as
+// MerkleProof's processMultiProof does. hashes are already normalized and checked.
+func (mc MerkleConfig) ozVerifyMultiProof(root string, leaves, hashes []string, flags []bool) (bool, error) {
+	queue := make([]string, len(leaves))
+	for i, leaf := range leaves {
+		queue[i] = NormalizeHex(leaf)
+		if err := mc.checkDigest(fmt.Sprintf("leaf %d", i), queue[i]); err != nil {
+			return false, err
+		}
+	}
+	// Lowercase hex digests sort like their bytes, which is the order of the
+	// leaves in the tree
+	sort.Strings(queue)
+	for i := 1; i < len(queue); i++ {
+		if queue[i] == queue[i-1] {
+			return false, fmt.Errorf("%w: leaf %s is given more than once", ErrInvalidProof, queue[i])
+		}
+	}
+	if len(queue)+len(hashes) != len(flags)+1 {
+		return false, fmt.Errorf("%w: %d leaves and %d hashes do not fit %d flags", ErrInvalidProof, len(queue), len(hashes), len(flags))
+	}
+
+	for _, flag := range flags {
+		if len(queue) == 0 {
+			return false, fmt.Errorf("%w: flags and hashes do not match the proven leaves", ErrInvalidProof)
+		}
+		a := queue[0]
+		queue = queue[1:]
+		var b string
+		switch {
+		case flag && len(queue) > 0:
+			b, queue = queue[0], queue[1:]
+		case !flag && len(hashes) > 0:
+			b, hashes = hashes[0], hashes[1:]
+		default:
+			return false, fmt.Errorf("%w: flags and hashes do not match the proven leaves", ErrInvalidProof)
+		}
+		queue = append(queue, mc.hashNode(a, b))
+	}
+
+	return len(queue) == 1 && queue[0] == root, nil
+}
+
+// sortedPositions returns the positions in set in increasing order
+func sortedPositions(set map[int]bool) []int {
+	positions := make([]int, 0, len(set))
+	for pos := range set {
+		positions = append(positions, pos)
+	}
+	sort.Ints(positions)
+	return positions
+}
@@ -0,0 +1,233 @@
+package clients_test
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"testing"
+
+	"github.com/Layer-Edge/light-node/clients"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestMultiProofRoundTrip(t *testing.T) {
+	configs := map[string]clients.MerkleConfig{
+		"default":    clients.DefaultMerkleConfig(),
+		"sort pairs": {Hasher: clients.Keccak256Hasher{}, LeafPrefix: []byte{0}, NodePrefix: []byte{1}, SortPairs: true},
+	}
+
+	for name, mc := range configs {
+		for size := 1; size <= 9; size++ {
+			leaves := make([]string, size)
+			for i := range leaves {
+				leaves[i] = fmt.Sprintf("leaf-%d", i)
+			}
+			root, err := mc.ComputeRoot(leaves)
+			if err != nil {
+				t.Fatalf("%s: ComputeRoot: %v", name, err)
+			}
+
+			// Every non-empty subset, proven in reverse order to check that the
+			// order of the given leaves is kept
+			for subset := 1; subset < 1<<size; subset++ {
+				var proven []string
+				for i := size - 1; i >= 0; i-- {
+					if subset&(1<<i) != 0 {
+						proven = append(proven, leaves[i])
+					}
+				}
+
+				proof, err := mc.GenerateMultiProof(leaves, proven)
+				if err != nil {
+					t.Fatalf("%s: GenerateMultiProof(%v): %v", name, proven, err)
+				}
+
+				// The proof travels as JSON
+				encoded, err := json.Marshal(proof)
+				if err != nil {
+					t.Fatalf("%s: Marshal: %v", name, err)
+				}
+				var decoded clients.MultiProof
+				if err := json.Unmarshal(encoded, &decoded); err != nil {
+					t.Fatalf("%s: Unmarshal: %v", name, err)
+				}
+
+				ok, err := mc.VerifyMultiProof(root, proven, decoded)
+				if err != nil || !ok {
+					t.Fatalf("%s: VerifyMultiProof(%v) of %d leaves = %v, %v, want true", name, proven, size, ok, err)
+				}
+			}
+		}
+	}
+}
+
+func TestMultiProofAgainstRisc0Root(t *testing.T) {
+	proven := []string{"elderberry", "banana", "cherry"}
+	proof, err := risc0Tree.GenerateMultiProof(proven)
+	if err != nil {
+		t.Fatalf("GenerateMultiProof: %v", err)
+	}
+	if ok, err := clients.VerifyMultiProof(risc0Tree.Root, proven, proof); err != nil || !ok {
+		t.Fatalf("VerifyMultiProof = %v, %v, want true", ok, err)
+	}
+
+	// A different leaf at a proven position no longer hashes to the root
+	if ok, err := clients.VerifyMultiProof(risc0Tree.Root, []string{"elderberry", "banana", "fig"}, proof); err != nil || ok {
+		t.Errorf("VerifyMultiProof with a wrong leaf = %v, %v, want false", ok, err)
+	}
+	// So do the right leaves given in another order
+	if ok, err := clients.VerifyMultiProof(risc0Tree.Root, []string{"banana", "elderberry", "cherry"}, proof); err != nil || ok {
+		t.Errorf("VerifyMultiProof with reordered leaves = %v, %v, want false", ok, err)
+	}
+}
+
+func TestMultiProofMalformed(t *testing.T) {
+	proven := []string{"apple", "date"}
+	proof, err := risc0Tree.GenerateMultiProof(proven)
+	if err != nil {
+		t.Fatalf("GenerateMultiProof: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		leaves []string
+		tamper func(p *clients.MultiProof)
+	}{
+		{name: "hash dropped", leaves: proven, tamper: func(p *clients.MultiProof) { p.Hashes = p.Hashes[1:] }},
+		{name: "extra hash", leaves: proven, tamper: func(p *clients.MultiProof) { p.Hashes = append(p.Hashes, p.Hashes[0]) }},
+		{name: "flag dropped", leaves: proven, tamper: func(p *clients.MultiProof) { p.Flags = p.Flags[1:] }},
+		{name: "hash not hex", leaves: proven, tamper: func(p *clients.MultiProof) { p.Hashes[0] = "not hex" }},
+		{name: "index outside the tree", leaves: proven, tamper: func(p *clients.MultiProof) { p.Indices[1] = p.LeafCount }},
+		{name: "index repeated", leaves: proven, tamper: func(p *clients.MultiProof) { p.Indices[1] = p.Indices[0] }},
+		{name: "fewer leaves than indices", leaves: proven[:1], tamper: func(*clients.MultiProof) {}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tampered := clients.MultiProof{
+				LeafCount: proof.LeafCount,
+				Indices:   append([]int(nil), proof.Indices...),
+				Hashes:    append([]string(nil), proof.Hashes...),
+				Flags:     append([]bool(nil), proof.Flags...),
+			}
+			tt.tamper(&tampered)
+			if _, err := clients.VerifyMultiProof(risc0Tree.Root, tt.leaves, tampered); !errors.Is(err, clients.ErrInvalidProof) {
+				t.Errorf("VerifyMultiProof error = %v, want ErrInvalidProof", err)
+			}
+		})
+	}
+
+	if _, err := risc0Tree.GenerateMultiProof([]string{"apple", "apple"}); !errors.Is(err, clients.ErrInvalidProof) {
+		t.Errorf("GenerateMultiProof of a repeated leaf error = %v, want ErrInvalidProof", err)
+	}
+	if _, err := risc0Tree.GenerateMultiProof([]string{"fig"}); !errors.Is(err, clients.ErrLeafNotFound) {
+		t.Errorf("GenerateMultiProof of a missing leaf error = %v, want ErrLeafNotFound", err)
+	}
+}
+
+// multiProofVerify is OpenZeppelin's MerkleProof.processMultiProof transcribed
+// from Solidity, taking the leaves in the order its callers pass them, sorted
+func multiProofVerify(proof []string, proofFlags []bool, root string, leaves []string) bool {
+	if len(leaves)+len(proof) != len(proofFlags)+1 {
+		return false
+	}
+	hashes := make([]string, len(proofFlags))
+	leafPos, hashPos, proofPos := 0, 0, 0
+	next := func() string {
+		if leafPos < len(leaves) {
+			leafPos++
+			return leaves[leafPos-1]
+		}
+		hashPos++
+		return hashes[hashPos-1]
+	}
+	for i, flag := range proofFlags {
+		a := next()
+		var b string
+		if flag {
+			b = next()
+		} else {
+			b = proof[proofPos]
+			proofPos++
+		}
+		hashes[i] = ozPair(a, b)
+	}
+	switch {
+	case len(proofFlags) > 0:
+		return proofPos == len(proof) && hashes[len(hashes)-1] == root
+	case len(leaves) > 0:
+		return leaves[0] == root
+	default:
+		return proof[0] == root
+	}
+}
+
+func TestOpenZeppelinMultiProof(t *testing.T) {
+	for size := 1; size <= 9; size++ {
+		leaves := make([]string, size)
+		for i := range leaves {
+			leaves[i] = hex.EncodeToString(crypto.Keccak256([]byte(strconv.Itoa(i))))
+		}
+		root, err := ozConfig.ComputeRoot(leaves)
+		if err != nil {
+			t.Fatalf("ComputeRoot: %v", err)
+		}
+
+		for subset := 1; subset < 1<<size; subset++ {
+			var proven []string
+			for i := size - 1; i >= 0; i-- {
+				if subset&(1<<i) != 0 {
+					proven = append(proven, leaves[i])
+				}
+			}
+
+			proof, err := ozConfig.GenerateMultiProof(leaves, proven)
+			if err != nil {
+				t.Fatalf("GenerateMultiProof(%v): %v", proven, err)
+			}
+			if ok, err := ozConfig.VerifyMultiProof(root, proven, proof); err != nil || !ok {
+				t.Fatalf("VerifyMultiProof(%v) of %d leaves = %v, %v, want true", proven, size, ok, err)
+			}
+
+			sorted := append([]string(nil), proven...)
+			sort.Strings(sorted)
+			if !multiProofVerify(proof.Hashes, proof.Flags, root, sorted) {
+				t.Fatalf("multiProofVerify rejected the proof of %v in a tree of %d leaves", proven, size)
+			}
+		}
+	}
+}
+
+func TestOpenZeppelinMultiProofLayout(t *testing.T) {
+	leaves := make([]string, 3)
+	for i, value := range []string{"a", "b", "c"} {
+		leaves[i] = hex.EncodeToString(crypto.Keccak256([]byte(value)))
+	}
+	sorted := append([]string(nil), leaves...)
+	sort.Strings(sorted)
+
+	// The node array is [root, parent of the two smallest, largest, middle,
+	// smallest]. Proving the smallest and largest takes the middle leaf as its
+	// sibling, then pairs the parent formed with the largest leaf.
+	proof, err := ozConfig.GenerateMultiProof(leaves, []string{sorted[0], sorted[2]})
+	if err != nil {
+		t.Fatalf("GenerateMultiProof: %v", err)
+	}
+	if len(proof.Hashes) != 1 || proof.Hashes[0] != sorted[1] {
+		t.Errorf("Hashes = %v, want [%s]", proof.Hashes, sorted[1])
+	}
+	if len(proof.Flags) != 2 || proof.Flags[0] || !proof.Flags[1] {
+		t.Errorf("Flags = %v, want [false true]", proof.Flags)
+	}
+
+	root, _ := ozConfig.ComputeRoot(leaves)
+	if ok, err := ozConfig.VerifyMultiProof(root, []string{sorted[0], sorted[1]}, proof); err != nil || ok {
+		t.Errorf("VerifyMultiProof with a wrong leaf = %v, %v, want false", ok, err)
+	}
+	if _, err := ozConfig.VerifyMultiProof(root, []string{sorted[0], sorted[0]}, proof); !errors.Is(err, clients.ErrInvalidProof) {
+		t.Errorf("VerifyMultiProof with a repeated leaf error = %v, want ErrInvalidProof", err)
+	}
+}
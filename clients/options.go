@@ -0,0 +1,189 @@
+package clients
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// Option customizes the configuration used by NewCosmosQueryClient
+type Option func(*ClientConfig)
+
+// WithConfig replaces the whole configuration, typically one loaded with
+// LoadClientConfigFromFile. Options after it are applied on top.
+func WithConfig(config ClientConfig) Option {
+	return func(c *ClientConfig) {
+		*c = config
+	}
+}
+
+// WithGrpcURL sets the gRPC endpoint to connect to
+func WithGrpcURL(url string) Option {
+	return func(c *ClientConfig) {
+		c.GrpcURL = url
+	}
+}
+
+// WithGrpcURLs sets failover endpoints tried in order, taking precedence over
+// WithGrpcURL
+func WithGrpcURLs(urls ...string) Option {
+	return func(c *ClientConfig) {
+		c.GrpcURLs = urls
+	}
+}
+
+// WithContractAddr sets the address of the merkle tree contract
+func WithContractAddr(addr string) Option {
+	return func(c *ClientConfig) {
+		c.ContractAddr = addr
+	}
+}
+
+// WithTLS enables TLS. serverName overrides the name the certificate is verified
+// against and caPath adds a PEM bundle of trusted CAs; either may be empty.
+func WithTLS(serverName, caPath string) Option {
+	return func(c *ClientConfig) {
+		c.UseTLS = true
+		c.TLSServerName = serverName
+		c.TLSCAPath = caPath
+	}
+}
+
+// WithClientCertificate presents a client certificate for mutual TLS
+func WithClientCertificate(certPath, keyPath string) Option {
+	return func(c *ClientConfig) {
+		c.TLSClientCertPath = certPath
+		c.TLSClientKeyPath = keyPath
+	}
+}
+
+// WithAuthToken sends token as a bearer token on every call
+func WithAuthToken(token string) Option {
+	return func(c *ClientConfig) {
+		c.AuthToken = token
+	}
+}
+
+// WithMetadataFunc adds the headers fn computes from each call's context, see
+// ClientConfig.MetadataFunc
+func WithMetadataFunc(fn func(ctx context.Context) metadata.MD) Option {
+	return func(c *ClientConfig) {
+		c.MetadataFunc = fn
+	}
+}
+
+// WithBlockLagCheck rejects trees a node answers more than maxLag blocks behind
+// the height reference returns, see ClientConfig.MaxBlockLag
+func WithBlockLagCheck(maxLag int, reference func(ctx context.Context) (int64, error)) Option {
+	return func(c *ClientConfig) {
+		c.MaxBlockLag = maxLag
+		c.ReferenceHeight = reference
+	}
+}
+
+// WithLCDFallback serves queries from the REST (LCD) endpoint at url whenever the
+// gRPC query fails with a transport error
+func WithLCDFallback(url string) Option {
+	return func(c *ClientConfig) {
+		c.LCDURL = url
+		c.LCDFallback = true
+	}
+}
+
+// WithRetries sets the connection retry policy. maxRetries of -1 retries forever.
+func WithRetries(maxRetries int, initialBackoff, maxBackoff time.Duration) Option {
+	return func(c *ClientConfig) {
+		c.MaxRetries = maxRetries
+		c.InitialBackoff = initialBackoff
+		c.MaxBackoff = maxBackoff
+	}
+}
+
+// WithQueryRetries sets how often a transiently failing query is retried and the
+// backoff between its retries
+func WithQueryRetries(maxRetries int, initialBackoff, maxBackoff time.Duration) Option {
+	return func(c *ClientConfig) {
+		c.QueryMaxRetries = maxRetries
+		c.QueryInitialBackoff = initialBackoff
+		c.QueryMaxBackoff = maxBackoff
+	}
+}
+
+// WithBackoff sets the strategy for the wait between connection retries
+func WithBackoff(backoff Backoff) Option {
+	return func(c *ClientConfig) {
+		c.Backoff = backoff
+	}
+}
+
+// WithQueryBackoff sets the strategy for the wait between query retries
+func WithQueryBackoff(backoff Backoff) Option {
+	return func(c *ClientConfig) {
+		c.QueryBackoff = backoff
+	}
+}
+
+// WithTimeouts sets the connection and per-query timeouts
+func WithTimeouts(connection, query time.Duration) Option {
+	return func(c *ClientConfig) {
+		c.ConnectionTimeout = connection
+		c.QueryTimeout = query
+	}
+}
+
+// WithDialOptions appends gRPC dial options, such as interceptors or a proxy
+// dialer, after the built-in ones
+func WithDialOptions(opts ...grpc.DialOption) Option {
+	return func(c *ClientConfig) {
+		c.DialOptions = append(c.DialOptions, opts...)
+	}
+}
+
+// WithInterceptors appends unary interceptors that run around every gRPC call
+func WithInterceptors(interceptors ...grpc.UnaryClientInterceptor) Option {
+	return func(c *ClientConfig) {
+		c.Interceptors = append(c.Interceptors, interceptors...)
+	}
+}
+
+// WithLazyConnect defers connecting until the first query
+func WithLazyConnect() Option {
+	return func(c *ClientConfig) {
+		c.LazyConnect = true
+	}
+}
+
+// WithLogger sets the logger for the client's events instead of the package logger
+func WithLogger(l *slog.Logger) Option {
+	return func(c *ClientConfig) {
+		c.Logger = l
+	}
+}
+
+// NewCosmosQueryClient builds a client from DefaultClientConfig with opts applied,
+// then validates the configuration and connects, unless LazyConnect is set. Unlike Init it never reads or
+// mutates the global configuration.
+func NewCosmosQueryClient(opts ...Option) (*CosmosQueryClient, error) {
+	return NewCosmosQueryClientContext(context.Background(), opts...)
+}
+
+// NewCosmosQueryClientContext is NewCosmosQueryClient with a context that aborts
+// the connection retry loop when cancelled
+func NewCosmosQueryClientContext(ctx context.Context, opts ...Option) (*CosmosQueryClient, error) {
+	config := DefaultClientConfig()
+	for _, opt := range opts {
+		opt(&config)
+	}
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	cqc := &CosmosQueryClient{config: config}
+	if err := cqc.start(ctx); err != nil {
+		return nil, err
+	}
+	return cqc, nil
+}
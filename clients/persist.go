@@ -0,0 +1,83 @@
+package clients
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// SaveTree writes t to path as JSON, in the same shape the contract returns. The
+// file is written to a temporary file in the same directory and renamed into
+// place, so readers never see a partly written tree. The saved file has mode
+// 0644, readable by everyone. A tree without leaves is saved with an empty list
+// rather than null.
+func SaveTree(path string, t *MerkleTree) (err error) {
+	if t == nil {
+		return errors.New("cannot save a nil merkle tree")
+	}
+
+	saved := t.clone()
+	if saved.Leaves == nil {
+		saved.Leaves = []string{}
+	}
+	data, err := json.MarshalIndent(saved, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode merkle tree: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file for %s: %w", path, err)
+	}
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	// CreateTemp makes the file private to its owner
+	if err = tmp.Chmod(0644); err != nil {
+		return fmt.Errorf("failed to set the mode of %s: %w", tmp.Name(), err)
+	}
+	if _, err = tmp.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write %s: %w", tmp.Name(), err)
+	}
+	if err = tmp.Sync(); err != nil {
+		return fmt.Errorf("failed to sync %s: %w", tmp.Name(), err)
+	}
+	if err = tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close %s: %w", tmp.Name(), err)
+	}
+	if err = os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to move merkle tree into %s: %w", path, err)
+	}
+	return nil
+}
+
+// LoadTree reads a tree written by SaveTree, or any file holding a get_merkle_tree
+// response. Missing or null leaves load as an empty list. A file that is empty or
+// holds null is an error wrapping ErrEmptyResponse or ErrTreeNotFound.
+func LoadTree(path string) (*MerkleTree, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read merkle tree: %w", err)
+	}
+	if len(data) == 0 {
+		return nil, fmt.Errorf("%w: %s is empty", ErrEmptyResponse, path)
+	}
+
+	var tree *MerkleTree
+	if err := json.Unmarshal(data, &tree); err != nil {
+		return nil, fmt.Errorf("%w: failed to decode merkle tree from %s: %w", ErrInvalidResponse, path, err)
+	}
+	if tree == nil {
+		return nil, fmt.Errorf("%w: %s holds null", ErrTreeNotFound, path)
+	}
+	if tree.Leaves == nil {
+		tree.Leaves = []string{}
+	}
+	return tree, nil
+}
@@ -0,0 +1,35 @@
+package clients_test
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/Layer-Edge/light-node/clients"
+)
+
+func TestSaveTree(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tree.json")
+	if err := clients.SaveTree(path, &risc0Tree); err != nil {
+		t.Fatalf("SaveTree: %v", err)
+	}
+
+	if runtime.GOOS != "windows" {
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if mode := info.Mode().Perm(); mode != 0644 {
+			t.Errorf("saved tree has mode %v, want -rw-r--r--", mode)
+		}
+	}
+
+	tree, err := clients.LoadTree(path)
+	if err != nil {
+		t.Fatalf("LoadTree: %v", err)
+	}
+	if err := tree.ValidateTree(); err != nil || tree.Root != risc0Tree.Root {
+		t.Errorf("LoadTree = %+v, %v, want the saved risc0 tree", tree, err)
+	}
+}
@@ -0,0 +1,56 @@
+package clients
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// HealthHandler returns an HTTP handler for liveness probes such as /healthz. It
+// answers 200 while IsConnected reports true and 503 otherwise, with the
+// connection state in the body. Serving it is left to the caller:
+//
+//	mux.Handle("/healthz", client.HealthHandler())
+//	mux.Handle("/readyz", client.ReadyHandler(2*time.Minute))
+func (cqc *CosmosQueryClient) HealthHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !cqc.IsConnected() {
+			writeProbe(w, http.StatusServiceUnavailable, "not connected: "+cqc.ConnectionState().String())
+			return
+		}
+		writeProbe(w, http.StatusOK, "ok")
+	})
+}
+
+// ReadyHandler returns an HTTP handler for readiness probes such as /readyz. Like
+// HealthHandler it needs IsConnected, and in addition a query must have succeeded
+// within maxAge, so a node whose connection looks fine but whose queries fail is
+// taken out of rotation. A maxAge of 0 accepts any earlier success. A client that
+// has not completed a query yet is not ready.
+func (cqc *CosmosQueryClient) ReadyHandler(maxAge time.Duration) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !cqc.IsConnected() {
+			writeProbe(w, http.StatusServiceUnavailable, "not connected: "+cqc.ConnectionState().String())
+			return
+		}
+
+		last := cqc.LastSuccessfulQuery()
+		switch {
+		case last.IsZero():
+			writeProbe(w, http.StatusServiceUnavailable, "no successful query yet")
+		case maxAge > 0 && time.Since(last) > maxAge:
+			writeProbe(w, http.StatusServiceUnavailable,
+				fmt.Sprintf("last successful query was %s ago, more than %s", time.Since(last).Round(time.Second), maxAge))
+		default:
+			writeProbe(w, http.StatusOK, "ok")
+		}
+	})
+}
+
+// writeProbe writes a plain-text probe response that is never cached
+func writeProbe(w http.ResponseWriter, code int, body string) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+	fmt.Fprintln(w, body)
+}
@@ -0,0 +1,114 @@
+package clients
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"google.golang.org/grpc"
+)
+
+// SmartContractRaw marshals query to JSON, runs it as a smart query against the
+// configured contract and returns the raw JSON response. Use it for contract
+// queries the package has no dedicated method for.
+func (cqc *CosmosQueryClient) SmartContractRaw(ctx context.Context, query any) ([]byte, error) {
+	return cqc.smartContractRaw(ctx, cqc.ContractAddr(), query)
+}
+
+// smartContractRaw runs query against the contract at addr, which stays the same
+// for every attempt even if UpdateContractAddr switches contracts meanwhile
+func (cqc *CosmosQueryClient) smartContractRaw(ctx context.Context, addr string, query any, opts ...grpc.CallOption) ([]byte, error) {
+	queryBytes, err := json.Marshal(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal query: %w", err)
+	}
+
+	ctx, requestID := cqc.withOutgoingRequestID(ctx)
+	res, err := cqc.smartContractState(ctx, addr, queryBytes, opts...)
+	if err != nil && cqc.shouldFallbackToLCD(err) {
+		data, lcdErr := cqc.lcdSmartQuery(ctx, addr, queryBytes)
+		if lcdErr == nil {
+			cqc.log().Warn("Contract query served by LCD after gRPC failure",
+				"contract_addr", addr, "request_id", requestID, "error", err)
+			return data, nil
+		}
+		err = fmt.Errorf("%w (LCD fallback also failed: %v)", err, lcdErr)
+	}
+	if err != nil {
+		cqc.log().Warn("Contract query failed", "query", string(queryBytes),
+			"contract_addr", addr, "request_id", requestID, "error", err)
+		qe := newQueryError(addr, err)
+		qe.RequestID = requestID
+		return nil, qe
+	}
+
+	return res.Data, nil
+}
+
+// QuerySmartContract runs query against the client's contract and decodes the JSON
+// response into a T. Decoding failures wrap ErrInvalidResponse.
+func QuerySmartContract[T any](ctx context.Context, cqc *CosmosQueryClient, query any) (T, error) {
+	data, err := cqc.SmartContractRaw(ctx, query)
+	if err != nil {
+		var result T
+		return result, err
+	}
+	return decodeResponse[T](cqc, data)
+}
+
+// decodeResponse decodes a raw contract response into a T. An empty response
+// wraps ErrEmptyResponse rather than surfacing as a JSON syntax error.
+func decodeResponse[T any](cqc *CosmosQueryClient, data []byte) (T, error) {
+	var result T
+	if len(bytes.TrimSpace(data)) == 0 {
+		return result, fmt.Errorf("%w from contract %s", ErrEmptyResponse, cqc.ContractAddr())
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return result, fmt.Errorf("%w: failed to unmarshal %T from contract %s: %w (payload: %s)",
+			ErrInvalidResponse, result, cqc.ContractAddr(), err, payloadSnippet(data))
+	}
+	return result, nil
+}
+
+// unwrapDataEnvelope returns the value of a response wrapped as {"data": ...}, as
+// newer versions of the contract answer, and any other response unchanged. Only
+// an object whose single key is "data" counts as an envelope; neither a tree nor a
+// list of tree IDs has that shape.
+func unwrapDataEnvelope(data []byte) []byte {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) == 0 || trimmed[0] != '{' {
+		return data
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(trimmed))
+	if _, err := dec.Token(); err != nil {
+		return data
+	}
+	if key, err := dec.Token(); err != nil || key != "data" {
+		return data
+	}
+	var value json.RawMessage
+	if err := dec.Decode(&value); err != nil {
+		return data
+	}
+	if end, err := dec.Token(); err != nil || end != json.Delim('}') {
+		return data
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return data
+	}
+	return value
+}
+
+// maxPayloadSnippet bounds how much of a response is quoted in error messages
+const maxPayloadSnippet = 256
+
+// payloadSnippet quotes the start of a response payload for error messages
+func payloadSnippet(data []byte) string {
+	if len(data) <= maxPayloadSnippet {
+		return fmt.Sprintf("%q", data)
+	}
+	return fmt.Sprintf("%q... (%d bytes)", data[:maxPayloadSnippet], len(data))
+}
@@ -0,0 +1,30 @@
+package clients
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestEmptyResponse(t *testing.T) {
+	cqc := &CosmosQueryClient{config: ClientConfig{ContractAddr: "wasm1contract"}}
+
+	for _, data := range []string{"", " ", "\n\t \r\n"} {
+		if _, err := decodeResponse[MerkleTree](cqc, []byte(data)); !errors.Is(err, ErrEmptyResponse) || errors.Is(err, ErrInvalidResponse) {
+			t.Errorf("decodeResponse(%q) error = %v, want ErrEmptyResponse only", data, err)
+		}
+		if _, err := decodeResponse[[]string](cqc, []byte(data)); !errors.Is(err, ErrEmptyResponse) {
+			t.Errorf("decodeResponse[[]string](%q) error = %v, want ErrEmptyResponse", data, err)
+		}
+		if _, err := cqc.newStreamDecoder([]byte(data)); !errors.Is(err, ErrEmptyResponse) || errors.Is(err, ErrInvalidResponse) {
+			t.Errorf("newStreamDecoder(%q) error = %v, want ErrEmptyResponse only", data, err)
+		}
+		if _, err := cqc.decodeTree([]byte(data), 0, nil); !errors.Is(err, ErrEmptyResponse) {
+			t.Errorf("decodeTree(%q) error = %v, want ErrEmptyResponse", data, err)
+		}
+	}
+
+	// A response that has content but is not JSON is invalid rather than empty
+	if _, err := decodeResponse[MerkleTree](cqc, []byte("{")); !errors.Is(err, ErrInvalidResponse) || errors.Is(err, ErrEmptyResponse) {
+		t.Errorf("decodeResponse(%q) error = %v, want ErrInvalidResponse only", "{", err)
+	}
+}
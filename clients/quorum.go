@@ -0,0 +1,192 @@
+package clients
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	wasmtypes "github.com/CosmWasm/wasmd/x/wasm/types"
+	"go.opentelemetry.io/otel/attribute"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+)
+
+// QuorumError reports that too few endpoints agreed on a tree for
+// GetMerkleTreeDataQuorum. It wraps ErrNoQuorum and every endpoint's failure, so
+// errors.Is matches either.
+type QuorumError struct {
+	ID     string
+	Quorum int
+	Roots  map[string][]string // Endpoints that answered, by the root they reported
+	Errors []EndpointError     // Endpoints that failed, in the configured order
+}
+
+func (e *QuorumError) Error() string {
+	roots := make([]string, 0, len(e.Roots))
+	for root := range e.Roots {
+		roots = append(roots, root)
+	}
+	sort.Strings(roots)
+
+	parts := make([]string, 0, len(roots)+1)
+	for _, root := range roots {
+		parts = append(parts, fmt.Sprintf("root %s from %s", root, strings.Join(e.Roots[root], ", ")))
+	}
+	if len(e.Errors) > 0 {
+		parts = append(parts, (&MultiEndpointError{Errors: e.Errors}).Error())
+	}
+	return fmt.Sprintf("%v: tree %s needs %d matching roots, got %s", ErrNoQuorum, e.ID, e.Quorum, strings.Join(parts, "; "))
+}
+
+func (e *QuorumError) Unwrap() []error {
+	errs := []error{ErrNoQuorum}
+	for _, err := range e.Errors {
+		errs = append(errs, err)
+	}
+	return errs
+}
+
+// quorumAnswer is one endpoint's answer to a quorum read
+type quorumAnswer struct {
+	endpoint string
+	tree     *MerkleTree
+	err      error
+}
+
+// GetMerkleTreeDataQuorum fetches the tree with the given ID from every configured
+// endpoint at once and returns it only when at least quorum of them report the
+// same root, guarding against a single lying or lagging node. Endpoints other than
+// the active one are dialed for the read and closed afterwards. Each endpoint gets
+// its own ConnectionTimeout and QueryTimeout, so a slow one cannot use up the
+// others' time, and the read returns as soon as quorum is reached.
+//
+// Only roots are compared; set VerifyRoot to also check each tree's leaves against
+// its root. When a quorum of endpoints report the tree missing the error wraps
+// ErrTreeNotFound; any other shortfall is a *QuorumError. Quorum reads bypass the
+// cache and the circuit breaker.
+func (cqc *CosmosQueryClient) GetMerkleTreeDataQuorum(ctx context.Context, id string, quorum int) (tree *MerkleTree, err error) {
+	ctx, end := cqc.startSpan(ctx, "GetMerkleTreeDataQuorum", attribute.String("tree.id", id), attribute.Int("quorum", quorum))
+	defer func() { end(err) }()
+
+	endpoints := cqc.config.Endpoints()
+	if quorum < 1 || quorum > len(endpoints) {
+		return nil, fmt.Errorf("quorum must be between 1 and the %d configured endpoints, got %d", len(endpoints), quorum)
+	}
+
+	done, err := cqc.beginQuery()
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	creds, err := cqc.transportCredentials()
+	if err != nil {
+		return nil, err
+	}
+	query := QueryGetTree{}
+	query.GetMerkleTree.ID = id
+	queryBytes, err := json.Marshal(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal query: %w", err)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	ctx, _ = cqc.withOutgoingRequestID(ctx)
+
+	addr := cqc.ContractAddr()
+	answers := make(chan quorumAnswer, len(endpoints))
+	for _, endpoint := range endpoints {
+		go func() {
+			tree, err := cqc.quorumFetch(ctx, endpoint, creds, addr, id, queryBytes)
+			answers <- quorumAnswer{endpoint: endpoint, tree: tree, err: err}
+		}()
+	}
+
+	qe := &QuorumError{ID: id, Quorum: quorum, Roots: make(map[string][]string)}
+	failed := make(map[string]error, len(endpoints))
+	notFound := 0
+	for range endpoints {
+		answer := <-answers
+		if answer.err != nil {
+			failed[answer.endpoint] = answer.err
+			if isNotFoundError(answer.err) || errors.Is(answer.err, ErrTreeNotFound) {
+				notFound++
+				if notFound >= quorum {
+					return nil, fmt.Errorf("%w: %s: reported missing by %d endpoints", ErrTreeNotFound, id, notFound)
+				}
+			}
+			continue
+		}
+
+		root := NormalizeHex(answer.tree.Root)
+		qe.Roots[root] = append(qe.Roots[root], answer.endpoint)
+		if len(qe.Roots[root]) >= quorum {
+			return answer.tree, nil
+		}
+	}
+
+	for _, endpoint := range endpoints {
+		if err, ok := failed[endpoint]; ok {
+			qe.Errors = append(qe.Errors, EndpointError{Endpoint: endpoint, Err: err})
+		}
+	}
+	for _, agreeing := range qe.Roots {
+		sort.Strings(agreeing)
+	}
+	cqc.log().Warn("Endpoints disagree on merkle tree", "tree_id", id, "error", qe)
+	return nil, qe
+}
+
+// quorumFetch queries one endpoint for a tree of the contract at addr, over the
+// live connection when the endpoint is the active one and over a connection of its
+// own otherwise
+func (cqc *CosmosQueryClient) quorumFetch(ctx context.Context, endpoint string, creds credentials.TransportCredentials, addr, id string, queryBytes []byte) (*MerkleTree, error) {
+	cqc.mu.RLock()
+	conn := cqc.conn
+	if cqc.activeEndpoint != endpoint {
+		conn = nil
+	}
+	cqc.mu.RUnlock()
+
+	if conn == nil {
+		var err error
+		conn, err = cqc.dial(ctx, endpoint, cqc.dialOptions(endpoint, creds))
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect: %w", err)
+		}
+		defer conn.Close()
+	}
+
+	if cqc.config.QueryTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cqc.config.QueryTimeout)
+		defer cancel()
+	}
+	if err := cqc.waitRateLimit(ctx); err != nil {
+		return nil, err
+	}
+
+	var header metadata.MD
+	opts := []grpc.CallOption{grpc.Header(&header)}
+	if cqc.config.WaitForReady {
+		opts = append(opts, grpc.WaitForReady(true))
+	}
+	res, err := wasmtypes.NewQueryClient(conn).SmartContractState(ctx, &wasmtypes.QuerySmartContractStateRequest{
+		Address:   addr,
+		QueryData: queryBytes,
+	}, opts...)
+	if err != nil {
+		return nil, err
+	}
+	tree, err := cqc.decodeMerkleTree(id, res.Data)
+	if err != nil {
+		return nil, err
+	}
+	tree.Height = blockHeightFromHeader(header)
+	return tree, nil
+}
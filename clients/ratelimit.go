@@ -0,0 +1,38 @@
+package clients
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"golang.org/x/time/rate"
+)
+
+// rateLimiter returns the limiter applied to outgoing queries, or nil when
+// RateLimitQPS is unset
+func (cqc *CosmosQueryClient) rateLimiter() *rate.Limiter {
+	if cqc.config.RateLimitQPS <= 0 {
+		return nil
+	}
+	cqc.limiterOnce.Do(func() {
+		burst := cqc.config.RateLimitBurst
+		if burst <= 0 {
+			burst = int(math.Max(1, math.Ceil(cqc.config.RateLimitQPS)))
+		}
+		cqc.limiter = rate.NewLimiter(rate.Limit(cqc.config.RateLimitQPS), burst)
+	})
+	return cqc.limiter
+}
+
+// waitRateLimit blocks until the rate limiter lets another query through. It
+// fails right away when ctx would expire before then.
+func (cqc *CosmosQueryClient) waitRateLimit(ctx context.Context) error {
+	limiter := cqc.rateLimiter()
+	if limiter == nil {
+		return nil
+	}
+	if err := limiter.Wait(ctx); err != nil {
+		return fmt.Errorf("waiting for the query rate limit: %w", err)
+	}
+	return nil
+}
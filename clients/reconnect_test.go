@@ -0,0 +1,69 @@
+package clients_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/Layer-Edge/light-node/clients"
+)
+
+// TestConcurrentQueriesDuringReconnect is meant for -race: queries snapshot the
+// connection while reconnects swap it underneath them, and none may fail because
+// its connection was closed mid-call
+func TestConcurrentQueriesDuringReconnect(t *testing.T) {
+	const (
+		workers    = 8
+		reconnects = 20
+	)
+
+	server, client := newFakeClient(t)
+	want := &clients.MerkleTree{Root: risc0Tree.Root, Leaves: risc0Tree.Leaves}
+	server.AddTree("tree-1", want)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, workers)
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ctx.Err() == nil {
+				tree, err := client.GetMerkleTreeDataContext(ctx, "tree-1")
+				if err == nil && tree.Root != want.Root {
+					t.Errorf("GetMerkleTreeDataContext root = %s, want %s", tree.Root, want.Root)
+				}
+				if err == nil {
+					_, err = client.ListMerkleTreeIdsContext(ctx)
+				}
+				if err == nil {
+					_, err = client.ContractInfo(ctx)
+				}
+				client.ConnectionState()
+				client.ActiveEndpoint()
+				if err != nil && ctx.Err() == nil {
+					errs <- err
+					return
+				}
+			}
+		}()
+	}
+
+	for range reconnects {
+		if err := client.Reconnect(ctx); err != nil {
+			t.Fatalf("Reconnect: %v", err)
+		}
+	}
+	cancel()
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Errorf("query during reconnect failed: %v", err)
+	}
+	if got := client.QueryStats().Reconnects; got != reconnects {
+		t.Errorf("QueryStats().Reconnects = %d, want %d", got, reconnects)
+	}
+}
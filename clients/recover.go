@@ -0,0 +1,20 @@
+package clients
+
+import (
+	"fmt"
+	"runtime/debug"
+)
+
+// runRecovered runs fn, recovering a panic so that a bug in a background goroutine
+// does not take down the process. The panic is logged with its stack and returned
+// as an error wrapping ErrPanic; it is nil when fn returns normally.
+func (cqc *CosmosQueryClient) runRecovered(name string, fn func()) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			cqc.log().Error("Recovered panic in background goroutine", "goroutine", name, "panic", r, "stack", string(debug.Stack()))
+			err = fmt.Errorf("%w in %s: %v", ErrPanic, name, r)
+		}
+	}()
+	fn()
+	return nil
+}
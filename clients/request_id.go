@@ -0,0 +1,43 @@
+package clients
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc/metadata"
+)
+
+// RequestIDHeader is the metadata key carrying the request ID of a query
+const RequestIDHeader = "x-request-id"
+
+type requestIDKey struct{}
+
+// ContextWithRequestID returns a context whose queries send id as their request
+// ID. An empty id generates a fresh UUID; RequestIDFromContext returns the value
+// used.
+func ContextWithRequestID(ctx context.Context, id string) context.Context {
+	if id == "" {
+		id = uuid.NewString()
+	}
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestIDFromContext returns the request ID set with ContextWithRequestID
+func RequestIDFromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(requestIDKey{}).(string)
+	return id, ok
+}
+
+// withOutgoingRequestID attaches the request ID for a query to the outgoing gRPC
+// metadata, generating one when ctx has none. Every retry of the query shares it.
+func (cqc *CosmosQueryClient) withOutgoingRequestID(ctx context.Context) (context.Context, string) {
+	if !cqc.config.SendRequestID {
+		return ctx, ""
+	}
+	id, ok := RequestIDFromContext(ctx)
+	if !ok {
+		ctx = ContextWithRequestID(ctx, "")
+		id, _ = RequestIDFromContext(ctx)
+	}
+	return metadata.AppendToOutgoingContext(ctx, RequestIDHeader, id), id
+}
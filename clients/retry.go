@@ -0,0 +1,198 @@
+package clients
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	wasmtypes "github.com/CosmWasm/wasmd/x/wasm/types"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// smartContractState runs a smart query against the contract at addr, retrying
+// transient failures up to QueryMaxRetries times after backing off as set by
+// QueryBackoff. A failure showing the connection is dead reconnects first.
+// Retries stop as soon as ctx is done. The whole exchange counts as one call for
+// the circuit breaker.
+func (cqc *CosmosQueryClient) smartContractState(ctx context.Context, addr string, queryBytes []byte, opts ...grpc.CallOption) (*wasmtypes.QuerySmartContractStateResponse, error) {
+	done, err := cqc.beginQuery()
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	cqc.stats.queries.Add(1)
+
+	if err := cqc.ensureConnected(ctx); err != nil {
+		cqc.stats.failures.Add(1)
+		return nil, err
+	}
+
+	threshold := cqc.config.FailureThreshold
+	if err := cqc.breaker.allow(threshold, cqc.config.OpenDuration); err != nil {
+		cqc.stats.failures.Add(1)
+		return nil, err
+	}
+
+	res, err := cqc.smartContractStateWithRetry(ctx, addr, queryBytes, opts)
+	cqc.breaker.record(threshold, err, cqc.log())
+	cqc.recordOutcome(err)
+	if err != nil {
+		cqc.stats.failures.Add(1)
+	}
+	return res, err
+}
+
+func (cqc *CosmosQueryClient) smartContractStateWithRetry(ctx context.Context, addr string, queryBytes []byte, opts []grpc.CallOption) (*wasmtypes.QuerySmartContractStateResponse, error) {
+	recvLimit := cqc.config.MaxRecvMsgSize
+	if recvLimit <= 0 {
+		recvLimit = defaultMaxRecvMsgSize
+	}
+
+	reconnected := false
+	for attempt := 0; ; attempt++ {
+		res, used, err := cqc.smartContractStateOnce(ctx, addr, queryBytes, opts)
+		if err == nil {
+			return res, nil
+		}
+
+		// A query cut off by a reconnect closing its connection is retried on the
+		// new one straight away, without counting as a retry
+		if cqc.superseded(ctx, used, err) {
+			attempt--
+			continue
+		}
+
+		// A dead connection is replaced once per query and the query retried on
+		// the fresh one straight away, without counting as a retry
+		if !reconnected && cqc.reconnectDead(ctx, err) {
+			reconnected = true
+			attempt--
+			continue
+		}
+
+		// A response over the receive limit is retried straight away with the
+		// limit doubled, without counting as a retry
+		if isMessageTooLarge(err) && recvLimit < maxAdaptiveRecvMsgSize && ctx.Err() == nil {
+			recvLimit = min(recvLimit*2, maxAdaptiveRecvMsgSize)
+			cqc.log().Warn("Contract response exceeded the receive limit, retrying with a larger limit; consider raising GRPC_MAX_RECV_MSG_SIZE",
+				"max_recv_msg_size", recvLimit, "contract_addr", addr, "error", err)
+			opts = append(opts, grpc.MaxCallRecvMsgSize(recvLimit))
+			attempt--
+			continue
+		}
+
+		if attempt >= cqc.config.QueryMaxRetries || !isRetryableQueryError(ctx, err) {
+			return nil, err
+		}
+
+		sleep := cqc.queryBackoff(attempt + 1)
+		cqc.stats.retries.Add(1)
+		cqc.log().Warn("Contract query failed, retrying", "attempt", attempt+1, "backoff", sleep,
+			"contract_addr", addr, "error", err)
+		if sleepBackoff(ctx, sleep) != nil {
+			return nil, err
+		}
+	}
+}
+
+// queryBackoff returns the wait before query retry attempt, 1 for the first retry
+func (cqc *CosmosQueryClient) queryBackoff(attempt int) time.Duration {
+	backoff := cqc.queryBackoffStrategy().NextBackoff(attempt)
+	if !cqc.config.QueryBackoffJitter {
+		return backoff
+	}
+	return cqc.fullJitter(backoff)
+}
+
+// smartContractStateOnce performs a single query attempt, also returning the query
+// client it used. When the caller's ctx has no deadline of its own the attempt is
+// bounded by QueryTimeout.
+func (cqc *CosmosQueryClient) smartContractStateOnce(ctx context.Context, addr string, queryBytes []byte, opts []grpc.CallOption) (*wasmtypes.QuerySmartContractStateResponse, wasmtypes.QueryClient, error) {
+	queryClient, err := cqc.currentQueryClient()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if _, hasDeadline := ctx.Deadline(); !hasDeadline && cqc.config.QueryTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cqc.config.QueryTimeout)
+		defer cancel()
+	}
+
+	if cqc.config.WaitForReady {
+		opts = append([]grpc.CallOption{grpc.WaitForReady(true)}, opts...)
+	}
+	if err := cqc.waitRateLimit(ctx); err != nil {
+		return nil, queryClient, err
+	}
+
+	res, err := queryClient.SmartContractState(
+		ctx,
+		&wasmtypes.QuerySmartContractStateRequest{
+			Address:   addr,
+			QueryData: queryBytes,
+		},
+		opts...,
+	)
+	return res, queryClient, err
+}
+
+// defaultMaxRecvMsgSize is gRPC's receive limit when MaxRecvMsgSize is unset
+const defaultMaxRecvMsgSize = 4 << 20
+
+// maxAdaptiveRecvMsgSize caps how far a query raises its receive limit after a
+// response turned out too large
+const maxAdaptiveRecvMsgSize = 128 << 20
+
+// isMessageTooLarge reports whether err is gRPC refusing a response over the
+// receive limit, as opposed to the server exhausting another resource
+func isMessageTooLarge(err error) bool {
+	s, ok := status.FromError(err)
+	return ok && s.Code() == codes.ResourceExhausted && strings.Contains(s.Message(), "larger than max")
+}
+
+// isRetryableQueryError reports whether a failed query is worth retrying. Only
+// transport-level failures are retried; contract errors such as InvalidArgument or
+// NotFound will not change on a second attempt.
+func isRetryableQueryError(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	if errors.Is(err, ErrReconnecting) {
+		return true
+	}
+
+	switch status.Code(err) {
+	case codes.Unavailable, codes.Aborted, codes.DeadlineExceeded:
+		return true
+	default:
+		return false
+	}
+}
+
+// sleepBackoff waits out a retry backoff of d. When ctx's deadline would pass
+// before d elapses there is no time left for the retry, so it returns
+// context.DeadlineExceeded right away instead of sleeping until the deadline.
+func sleepBackoff(ctx context.Context, d time.Duration) error {
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) <= d {
+		return context.DeadlineExceeded
+	}
+	return sleepContext(ctx, d)
+}
+
+// sleepContext waits for d, returning early with ctx.Err() if ctx is done first
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,164 @@
+package clients
+
+import (
+	"context"
+	"fmt"
+
+	wasmtypes "github.com/CosmWasm/wasmd/x/wasm/types"
+	query "github.com/cosmos/cosmos-sdk/types/query"
+	"google.golang.org/grpc"
+)
+
+// allStatePageLimit is the number of entries AllContractState requests per page
+const allStatePageLimit = 100
+
+// RawContractState reads the value stored under key in the contract's storage,
+// bypassing its smart queries. A key with no value yields nil data and no error.
+// Raw reads are meant for debugging and are neither retried nor counted by the
+// circuit breaker.
+func (cqc *CosmosQueryClient) RawContractState(ctx context.Context, key []byte) ([]byte, error) {
+	var data []byte
+	err := cqc.directQuery(ctx, func(ctx context.Context, queryClient wasmtypes.QueryClient) error {
+		res, err := queryClient.RawContractState(ctx, &wasmtypes.QueryRawContractStateRequest{
+			Address:   cqc.ContractAddr(),
+			QueryData: key,
+		})
+		if err != nil {
+			return err
+		}
+		data = res.Data
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read raw contract state: %w", err)
+	}
+	return data, nil
+}
+
+// AllContractState returns every key/value pair in the contract's storage,
+// following pagination until the node reports no more pages. Each page is bounded
+// by QueryTimeout; ctx bounds the whole walk. Like RawContractState it is meant for
+// debugging and can be large on a busy contract.
+func (cqc *CosmosQueryClient) AllContractState(ctx context.Context) ([]wasmtypes.Model, error) {
+	// Every page comes from the same contract, even if UpdateContractAddr runs
+	addr := cqc.ContractAddr()
+	models := []wasmtypes.Model{}
+	var next []byte
+	for {
+		err := cqc.directQuery(ctx, func(ctx context.Context, queryClient wasmtypes.QueryClient) error {
+			res, err := queryClient.AllContractState(ctx, &wasmtypes.QueryAllContractStateRequest{
+				Address:    addr,
+				Pagination: &query.PageRequest{Key: next, Limit: allStatePageLimit},
+			})
+			if err != nil {
+				return err
+			}
+			models = append(models, res.Models...)
+			next = nil
+			if res.Pagination != nil {
+				next = res.Pagination.NextKey
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to read contract state after %d entries: %w", len(models), err)
+		}
+		if len(next) == 0 {
+			return models, nil
+		}
+	}
+}
+
+// directQuery runs a single wasm query outside the smart-query retry path. It
+// still waits for a LazyConnect connection and the rate limiter, bounds the call
+// by QueryTimeout when ctx has no deadline, sends the request ID header, and
+// reports failures as a *QueryError. A call cut off by a reconnect is repeated
+// once on the new connection.
+func (cqc *CosmosQueryClient) directQuery(ctx context.Context, call func(context.Context, wasmtypes.QueryClient) error) error {
+	done, err := cqc.beginQuery()
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	if err := cqc.ensureConnected(ctx); err != nil {
+		return err
+	}
+	queryClient, err := cqc.currentQueryClient()
+	if err != nil {
+		return err
+	}
+
+	if _, hasDeadline := ctx.Deadline(); !hasDeadline && cqc.config.QueryTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cqc.config.QueryTimeout)
+		defer cancel()
+	}
+
+	if err := cqc.waitRateLimit(ctx); err != nil {
+		return err
+	}
+
+	ctx, requestID := cqc.withOutgoingRequestID(ctx)
+	err = call(ctx, queryClient)
+	if cqc.superseded(ctx, queryClient, err) {
+		if queryClient, err = cqc.currentQueryClient(); err == nil {
+			err = call(ctx, queryClient)
+		}
+	}
+	if err != nil {
+		qe := newQueryError(cqc.ContractAddr(), err)
+		qe.RequestID = requestID
+		return qe
+	}
+	return nil
+}
+
+// ContractInfo returns the contract's metadata: code ID, creator, admin and label.
+// It is fetched once per connection and contract address, or taken from the
+// connection's verification under VerifyContractInfo, and refetched after a
+// reconnect or UpdateContractAddr.
+func (cqc *CosmosQueryClient) ContractInfo(ctx context.Context) (*wasmtypes.ContractInfo, error) {
+	cqc.mu.RLock()
+	conn, addr := cqc.conn, cqc.config.ContractAddr
+	cqc.mu.RUnlock()
+
+	cqc.infoMu.Lock()
+	if cqc.info != nil && cqc.infoConn == conn && cqc.infoAddr == addr && conn != nil {
+		info := *cqc.info
+		cqc.infoMu.Unlock()
+		return &info, nil
+	}
+	cqc.infoMu.Unlock()
+
+	var info wasmtypes.ContractInfo
+	err := cqc.directQuery(ctx, func(ctx context.Context, queryClient wasmtypes.QueryClient) error {
+		res, err := queryClient.ContractInfo(ctx, &wasmtypes.QueryContractInfoRequest{
+			Address: addr,
+		})
+		if err != nil {
+			return err
+		}
+		info = res.ContractInfo
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get contract info: %w", err)
+	}
+
+	// A LazyConnect client may only have connected during the query
+	cqc.mu.RLock()
+	conn = cqc.conn
+	cqc.mu.RUnlock()
+	cqc.storeContractInfo(conn, addr, info)
+	return &info, nil
+}
+
+// storeContractInfo caches info as fetched over conn for the contract at addr
+func (cqc *CosmosQueryClient) storeContractInfo(conn *grpc.ClientConn, addr string, info wasmtypes.ContractInfo) {
+	cqc.infoMu.Lock()
+	defer cqc.infoMu.Unlock()
+	cqc.info = &info
+	cqc.infoConn = conn
+	cqc.infoAddr = addr
+}
@@ -0,0 +1,99 @@
+package clients
+
+import (
+	"expvar"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// expvarName is the expvar variable a client publishes its stats under when
+// EnableExpvar is set
+const expvarName = "cosmos_query_client"
+
+// expvarMu serializes checking for and publishing expvarName, as expvar.Publish
+// panics on a duplicate name
+var expvarMu sync.Mutex
+
+// QueryStats is a snapshot of the query counters kept by a CosmosQueryClient
+type QueryStats struct {
+	Queries       uint64    // Contract queries issued by callers
+	Retries       uint64    // Retries performed after transient failures
+	Failures      uint64    // Queries that ultimately failed
+	Reconnects    uint64    // Connections rebuilt by the health check or after a query found the connection dead
+	LastConnected time.Time // When the current connection was established, zero if never
+}
+
+// clientStats holds the live counters behind QueryStats
+type clientStats struct {
+	queries       atomic.Uint64
+	retries       atomic.Uint64
+	failures      atomic.Uint64
+	reconnects    atomic.Uint64
+	lastConnected atomic.Int64 // Unix nanoseconds
+}
+
+// QueryStats returns the current query counters, useful for telling a real outage
+// (failures) apart from a flaky network (retries that eventually succeed)
+func (cqc *CosmosQueryClient) QueryStats() QueryStats {
+	return QueryStats{
+		Queries:       cqc.stats.queries.Load(),
+		Retries:       cqc.stats.retries.Load(),
+		Failures:      cqc.stats.failures.Load(),
+		Reconnects:    cqc.stats.reconnects.Load(),
+		LastConnected: unixNanoTime(cqc.stats.lastConnected.Load()),
+	}
+}
+
+// recordOutcome remembers when a query last succeeded, or what it last failed with
+func (cqc *CosmosQueryClient) recordOutcome(err error) {
+	now := time.Now()
+	cqc.mu.Lock()
+	defer cqc.mu.Unlock()
+	if err == nil {
+		cqc.lastSuccess = now
+		return
+	}
+	cqc.lastErr = err
+	cqc.lastErrAt = now
+}
+
+// LastSuccessfulQuery returns when a query last got an answer from the node, or
+// the zero time if none has. Alerting on its age catches a node that stopped
+// answering while the connection still looks Ready.
+func (cqc *CosmosQueryClient) LastSuccessfulQuery() time.Time {
+	cqc.mu.RLock()
+	defer cqc.mu.RUnlock()
+	return cqc.lastSuccess
+}
+
+// LastError returns when the most recent query failure happened and what it was,
+// or the zero time and nil if no query has failed
+func (cqc *CosmosQueryClient) LastError() (time.Time, error) {
+	cqc.mu.RLock()
+	defer cqc.mu.RUnlock()
+	return cqc.lastErrAt, cqc.lastErr
+}
+
+// unixNanoTime converts Unix nanoseconds to a time, keeping 0 as the zero time
+func unixNanoTime(nanos int64) time.Time {
+	if nanos == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, nanos)
+}
+
+// publishExpvar exports the client's stats under expvarName, where they show up
+// on /debug/vars. Only one client per process can hold the name; later ones log a
+// warning and stay unpublished.
+func (cqc *CosmosQueryClient) publishExpvar() {
+	expvarMu.Lock()
+	defer expvarMu.Unlock()
+	if expvar.Get(expvarName) != nil {
+		cqc.log().Warn("expvar stats already published by another client", "name", expvarName)
+		return
+	}
+	expvar.Publish(expvarName, expvar.Func(func() any {
+		return cqc.QueryStats()
+	}))
+}
@@ -0,0 +1,238 @@
+package clients
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// StreamMerkleTree fetches the tree with the given ID and calls fn with each leaf,
+// in order, as it is decoded, instead of collecting the leaves into a slice. The
+// returned tree carries Root and Metadata and has nil Leaves. The response itself
+// is still received whole, so this saves the memory of the decoded leaves, which
+// for trees with hundreds of thousands of leaves is most of it.
+//
+// An error returned by fn stops decoding and is returned as is. Streamed trees
+// bypass the cache, and StrictLeaves, VerifyRoot and DedupLeaves do not apply to
+// them; GetMerkleTreeData remains the simpler method for trees of modest size.
+func (cqc *CosmosQueryClient) StreamMerkleTree(ctx context.Context, id string, fn func(leaf string) error) (tree *MerkleTree, err error) {
+	ctx, end := cqc.startSpan(ctx, "StreamMerkleTree")
+	defer func() { end(err) }()
+
+	data, err := cqc.getMerkleTreeDataRaw(ctx, cqc.ContractAddr(), id)
+	if err != nil {
+		return nil, err
+	}
+
+	tree, err = cqc.decodeTreeStream(unwrapDataEnvelope(data), fn)
+	if err != nil {
+		if callbackErr, ok := err.(errStreamCallback); ok {
+			return nil, callbackErr.err
+		}
+		return nil, fmt.Errorf("failed to decode merkle tree %s: %w", id, err)
+	}
+	if tree == nil {
+		return nil, fmt.Errorf("%w: %s", ErrTreeNotFound, id)
+	}
+	return tree, nil
+}
+
+// StreamMerkleTreeIds lists the IDs of all merkle trees stored in the contract,
+// calling fn with each ID as it is decoded. An error returned by fn stops decoding
+// and is returned as is.
+func (cqc *CosmosQueryClient) StreamMerkleTreeIds(ctx context.Context, fn func(id string) error) (err error) {
+	ctx, end := cqc.startSpan(ctx, "StreamMerkleTreeIds")
+	defer func() { end(err) }()
+
+	data, err := cqc.ListMerkleTreeIdsRaw(ctx)
+	if err != nil {
+		return err
+	}
+
+	data = unwrapDataEnvelope(data)
+	dec, err := cqc.newStreamDecoder(data)
+	if err == nil {
+		_, err = cqc.streamStrings(dec, data, 0, fn)
+	}
+	if err == nil {
+		err = cqc.streamEnd(dec, data)
+	}
+	if err != nil {
+		if callbackErr, ok := err.(errStreamCallback); ok {
+			return callbackErr.err
+		}
+		return fmt.Errorf("failed to decode merkle tree ids: %w", err)
+	}
+	return nil
+}
+
+// errStreamCallback carries an error returned by a streaming callback up to the
+// caller, which unwraps it rather than reporting an invalid response
+type errStreamCallback struct {
+	err error
+}
+
+func (e errStreamCallback) Error() string { return e.err.Error() }
+
+// decodeTreeStream decodes a get_merkle_tree response token by token, passing each
+// leaf to fn. It returns nil when the contract answered null. Object keys match
+// the MerkleTree fields case-insensitively, as they do for json.Unmarshal, and
+// unknown keys are skipped.
+func (cqc *CosmosQueryClient) decodeTreeStream(data []byte, fn func(leaf string) error) (*MerkleTree, error) {
+	return cqc.decodeTree(data, 0, fn)
+}
+
+// decodeTree is decodeTreeStream, failing once more than limit leaves are decoded
+// unless limit is 0. A nil fn collects the leaves into the returned tree with
+// json.Unmarshal instead, which takes half the time and allocations of decoding
+// token by token, and checks limit once the tree is decoded.
+func (cqc *CosmosQueryClient) decodeTree(data []byte, limit int, fn func(leaf string) error) (*MerkleTree, error) {
+	if fn == nil {
+		tree, err := decodeResponse[*MerkleTree](cqc, data)
+		if err != nil {
+			return nil, err
+		}
+		if tree != nil {
+			if err := cqc.checkLimit(data, len(tree.Leaves), limit); err != nil {
+				return nil, err
+			}
+		}
+		return tree, nil
+	}
+
+	dec, err := cqc.newStreamDecoder(data)
+	if err != nil {
+		return nil, err
+	}
+
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, cqc.streamError(data, err)
+	}
+	if tok == nil {
+		return nil, cqc.streamEnd(dec, data)
+	}
+	if tok != json.Delim('{') {
+		return nil, cqc.streamError(data, fmt.Errorf("expected an object, found %v", tok))
+	}
+
+	tree := &MerkleTree{}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, cqc.streamError(data, err)
+		}
+		key, _ := tok.(string)
+		switch strings.ToLower(key) {
+		case "root":
+			err = dec.Decode(&tree.Root)
+		case "metadata":
+			err = dec.Decode(&tree.Metadata)
+		case "leaves":
+			// Already wrapped, or a callback error
+			if _, err = cqc.streamStrings(dec, data, limit, fn); err != nil {
+				return nil, err
+			}
+		default:
+			err = dec.Decode(&json.RawMessage{})
+		}
+		if err != nil {
+			return nil, cqc.streamError(data, err)
+		}
+	}
+	if _, err := dec.Token(); err != nil {
+		return nil, cqc.streamError(data, err)
+	}
+	return tree, cqc.streamEnd(dec, data)
+}
+
+// decodeIds decodes a list_merkle_tree_ids response with json.Unmarshal, failing
+// when it holds more than limit IDs unless limit is 0. It returns nil when the
+// contract answered null.
+func (cqc *CosmosQueryClient) decodeIds(data []byte, limit int) ([]string, error) {
+	ids, err := decodeResponse[[]string](cqc, data)
+	if err != nil {
+		return nil, err
+	}
+	if err := cqc.checkLimit(data, len(ids), limit); err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// checkLimit fails with ErrInvalidResponse when a decoded list of n entries has
+// more than limit, unless limit is 0
+func (cqc *CosmosQueryClient) checkLimit(data []byte, n, limit int) error {
+	if limit > 0 && n > limit {
+		return cqc.streamError(data, fmt.Errorf("list has %d entries, more than %d, see MaxLeaves", n, limit))
+	}
+	return nil
+}
+
+// newStreamDecoder returns a decoder over data, reporting an empty response as
+// decodeResponse does
+func (cqc *CosmosQueryClient) newStreamDecoder(data []byte) (*json.Decoder, error) {
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil, fmt.Errorf("%w from contract %s", ErrEmptyResponse, cqc.ContractAddr())
+	}
+	return json.NewDecoder(bytes.NewReader(data)), nil
+}
+
+// streamStrings decodes a JSON array of strings, or null, passing each element to
+// fn and reporting whether it was null. More than limit elements is an error,
+// unless limit is 0.
+func (cqc *CosmosQueryClient) streamStrings(dec *json.Decoder, data []byte, limit int, fn func(string) error) (bool, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return false, cqc.streamError(data, err)
+	}
+	if tok == nil {
+		return true, nil
+	}
+	if tok != json.Delim('[') {
+		return false, cqc.streamError(data, fmt.Errorf("expected a list, found %v", tok))
+	}
+
+	for n := 0; dec.More(); n++ {
+		if limit > 0 && n == limit {
+			return false, cqc.streamError(data, fmt.Errorf("list has more than %d entries, see MaxLeaves", limit))
+		}
+		var s string
+		if err := dec.Decode(&s); err != nil {
+			return false, cqc.streamError(data, err)
+		}
+		if err := fn(s); err != nil {
+			return false, errStreamCallback{err}
+		}
+	}
+	if _, err := dec.Token(); err != nil {
+		return false, cqc.streamError(data, err)
+	}
+	return false, nil
+}
+
+// streamEnd checks that nothing but whitespace follows the decoded value, as
+// json.Unmarshal does
+func (cqc *CosmosQueryClient) streamEnd(dec *json.Decoder, data []byte) error {
+	if _, err := dec.Token(); err != io.EOF {
+		return cqc.streamError(data, errors.New("unexpected data after the top-level value"))
+	}
+	return nil
+}
+
+// streamError wraps a decoding failure in ErrInvalidResponse, passing callback
+// errors through unchanged
+func (cqc *CosmosQueryClient) streamError(data []byte, err error) error {
+	if _, ok := err.(errStreamCallback); ok {
+		return err
+	}
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return fmt.Errorf("%w: failed to decode contract %s response: %w (payload: %s)",
+		ErrInvalidResponse, cqc.ContractAddr(), err, payloadSnippet(data))
+}
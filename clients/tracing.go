@@ -0,0 +1,53 @@
+package clients
+
+import (
+	"context"
+
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+// tracerName identifies the spans started by this package
+const tracerName = "github.com/Layer-Edge/light-node/clients"
+
+// tracerProvider returns the configured provider, or the global one
+func (cqc *CosmosQueryClient) tracerProvider() trace.TracerProvider {
+	if cqc.config.TracerProvider != nil {
+		return cqc.config.TracerProvider
+	}
+	return otel.GetTracerProvider()
+}
+
+// tracingDialOption instruments every gRPC call on the connection with a span
+func (cqc *CosmosQueryClient) tracingDialOption() grpc.DialOption {
+	return grpc.WithStatsHandler(otelgrpc.NewClientHandler(otelgrpc.WithTracerProvider(cqc.tracerProvider())))
+}
+
+// startSpan starts a span for a client operation as a child of any span in ctx.
+// The returned function ends it, recording err and its gRPC status code. With
+// tracing disabled ctx is returned unchanged and ending is a no-op.
+func (cqc *CosmosQueryClient) startSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, func(error)) {
+	if !cqc.config.EnableTracing {
+		return ctx, func(error) {}
+	}
+
+	attrs = append(attrs, attribute.String("contract.addr", cqc.ContractAddr()))
+	ctx, span := cqc.tracerProvider().Tracer(tracerName).Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+
+	return ctx, func(err error) {
+		defer span.End()
+		span.SetAttributes(attribute.String("result.status", status.Code(err).String()))
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(otelcodes.Error, err.Error())
+			return
+		}
+		span.SetStatus(otelcodes.Ok, "")
+	}
+}
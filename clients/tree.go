@@ -0,0 +1,196 @@
+package clients
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ValidateLeaves checks that every leaf is hex, optionally 0x-prefixed, and that
+// all leaves decode to the same number of bytes. It suits contracts that store
+// leaf digests; the production contract stores arbitrary strings, which are hashed
+// as text and would fail this check. Failures wrap ErrInvalidLeaf.
+func (t *MerkleTree) ValidateLeaves() error {
+	size := -1
+	for i, leaf := range t.Leaves {
+		digits := strings.TrimPrefix(strings.TrimPrefix(leaf, "0x"), "0X")
+		decoded, err := hex.DecodeString(digits)
+		if err != nil {
+			return fmt.Errorf("%w: leaf %d (%q) is not valid hex: %v", ErrInvalidLeaf, i, leaf, err)
+		}
+		if len(decoded) == 0 {
+			return fmt.Errorf("%w: leaf %d is empty", ErrInvalidLeaf, i)
+		}
+		if size == -1 {
+			size = len(decoded)
+		} else if len(decoded) != size {
+			return fmt.Errorf("%w: leaf %d is %d bytes, expected %d like the first leaf", ErrInvalidLeaf, i, len(decoded), size)
+		}
+	}
+	return nil
+}
+
+// DedupLeaves returns a copy of the tree with repeated leaves removed, keeping the
+// first occurrence of each in order. Leaves must be identical to count as
+// repeats, as the contract hashes them as text. Root is copied unchanged, so when
+// anything was removed the leaves generally no longer hash to it: the root commits
+// to every leaf position, duplicates included. Proofs for the deduplicated leaves
+// only verify against a root computed from them.
+func (t *MerkleTree) DedupLeaves() *MerkleTree {
+	deduped := t.clone()
+	if t.Leaves == nil {
+		return deduped
+	}
+
+	seen := make(map[string]struct{}, len(t.Leaves))
+	deduped.Leaves = deduped.Leaves[:0]
+	for _, leaf := range t.Leaves {
+		if _, ok := seen[leaf]; ok {
+			continue
+		}
+		seen[leaf] = struct{}{}
+		deduped.Leaves = append(deduped.Leaves, leaf)
+	}
+	return deduped
+}
+
+// ParseMetadata decodes the tree's Metadata string, which is left untouched. A JSON
+// object is decoded as is. Anything else is read as key=value pairs separated by
+// commas, semicolons or newlines, with values kept as strings. Empty metadata
+// yields an empty map. The string is parsed on every call, so callers that need it
+// repeatedly should keep the result.
+func (t *MerkleTree) ParseMetadata() (map[string]any, error) {
+	raw := strings.TrimSpace(t.Metadata)
+	if raw == "" {
+		return map[string]any{}, nil
+	}
+
+	if strings.HasPrefix(raw, "{") {
+		var metadata map[string]any
+		if err := json.Unmarshal([]byte(raw), &metadata); err != nil {
+			return nil, fmt.Errorf("%w: metadata is not a valid JSON object: %w", ErrInvalidMetadata, err)
+		}
+		return metadata, nil
+	}
+
+	metadata := make(map[string]any)
+	fields := strings.FieldsFunc(raw, func(r rune) bool {
+		return r == ',' || r == ';' || r == '\n'
+	})
+	for _, field := range fields {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(field, "=")
+		if !ok || strings.TrimSpace(key) == "" {
+			return nil, fmt.Errorf("%w: %q is neither a JSON object nor key=value pairs", ErrInvalidMetadata, t.Metadata)
+		}
+		metadata[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+	return metadata, nil
+}
+
+// NormalizeHex returns the canonical spelling of a hex value: any 0x prefix is
+// dropped and the digits are lowercased, the form ComputeRoot and proofs use.
+// Only the representation changes, not the bytes it encodes. Anything that is not
+// hex is returned unchanged, so it is safe to apply to leaves of either kind.
+//
+// Leaves themselves are hashed as text, so "0xAB" and "ab" hash differently.
+// Normalize leaves to compare them, never before hashing them.
+func NormalizeHex(s string) string {
+	digits := strings.TrimPrefix(strings.TrimPrefix(s, "0x"), "0X")
+	if digits == "" {
+		return s
+	}
+	for _, r := range digits {
+		if !strings.ContainsRune("0123456789abcdefABCDEF", r) {
+			return s
+		}
+	}
+	return strings.ToLower(digits)
+}
+
+// LeafIndex returns the position of the first leaf equal to leaf and whether one
+// was found. An exact match is preferred; failing that, hex leaves match regardless
+// of case and 0x prefix, so "0xAB" finds "ab". It scans the leaves, so it is O(n)
+// per call; use ContainsLeaves to check many leaves at once.
+func (t *MerkleTree) LeafIndex(leaf string) (int, bool) {
+	for i, l := range t.Leaves {
+		if l == leaf {
+			return i, true
+		}
+	}
+
+	normalized := NormalizeHex(leaf)
+	for i, l := range t.Leaves {
+		if NormalizeHex(l) == normalized {
+			return i, true
+		}
+	}
+	return -1, false
+}
+
+// ContainsLeaves reports for each candidate whether it is one of the tree's leaves,
+// matching as LeafIndex does. The tree's leaves are indexed once, so checking many
+// candidates costs O(n + m) rather than O(n * m).
+func (t *MerkleTree) ContainsLeaves(leaves []string) map[string]bool {
+	exact := make(map[string]struct{}, len(t.Leaves))
+	normalized := make(map[string]struct{}, len(t.Leaves))
+	for _, l := range t.Leaves {
+		exact[l] = struct{}{}
+		normalized[NormalizeHex(l)] = struct{}{}
+	}
+
+	found := make(map[string]bool, len(leaves))
+	for _, leaf := range leaves {
+		if _, ok := exact[leaf]; ok {
+			found[leaf] = true
+			continue
+		}
+		_, ok := normalized[NormalizeHex(leaf)]
+		found[leaf] = ok
+	}
+	return found
+}
+
+// DiffTrees compares the leaves of two trees as sets, returning the leaves of b that
+// a lacks and the leaves of a that b lacks, each sorted. Leaves are compared in the
+// normalized form used by LeafIndex. A nil tree counts as having no leaves.
+func DiffTrees(a, b *MerkleTree) (added, removed []string) {
+	aLeaves, bLeaves := leafSet(a), leafSet(b)
+
+	added = []string{}
+	for key, leaf := range bLeaves {
+		if _, ok := aLeaves[key]; !ok {
+			added = append(added, leaf)
+		}
+	}
+	removed = []string{}
+	for key, leaf := range aLeaves {
+		if _, ok := bLeaves[key]; !ok {
+			removed = append(removed, leaf)
+		}
+	}
+
+	sort.Strings(added)
+	sort.Strings(removed)
+	return added, removed
+}
+
+// leafSet maps each normalized leaf of t to the first leaf with that form
+func leafSet(t *MerkleTree) map[string]string {
+	if t == nil {
+		return map[string]string{}
+	}
+	set := make(map[string]string, len(t.Leaves))
+	for _, leaf := range t.Leaves {
+		key := NormalizeHex(leaf)
+		if _, ok := set[key]; !ok {
+			set[key] = leaf
+		}
+	}
+	return set
+}
@@ -0,0 +1,76 @@
+package clients
+
+import (
+	"context"
+	"time"
+)
+
+// defaultWatchInterval is used by WatchTreeIDs when no positive interval is given
+const defaultWatchInterval = 30 * time.Second
+
+// WatchTreeIDs polls ListMerkleTreeIds every interval and sends each tree ID the
+// first time it is seen, so the first poll sends every existing ID. Failed polls
+// are sent on the error channel and polling carries on. Both channels are closed
+// once ctx is done; a slow reader delays the next poll rather than losing IDs. A
+// panic during a poll is sent on the error channel as an error wrapping ErrPanic.
+func (cqc *CosmosQueryClient) WatchTreeIDs(ctx context.Context, interval time.Duration) (<-chan string, <-chan error) {
+	if interval <= 0 {
+		interval = defaultWatchInterval
+	}
+
+	ids := make(chan string)
+	errs := make(chan error)
+
+	go func() {
+		defer close(ids)
+		defer close(errs)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		seen := make(map[string]struct{})
+		poll := func() {
+			current, err := cqc.ListMerkleTreeIdsContext(ctx)
+			if err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				select {
+				case errs <- err:
+				case <-ctx.Done():
+					return
+				}
+			}
+
+			for _, id := range current {
+				if _, ok := seen[id]; ok {
+					continue
+				}
+				select {
+				case ids <- id:
+					seen[id] = struct{}{}
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+
+		for {
+			if err := cqc.runRecovered("tree ID watcher", poll); err != nil {
+				select {
+				case errs <- err:
+				case <-ctx.Done():
+					return
+				}
+			}
+
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return ids, errs
+}